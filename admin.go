@@ -0,0 +1,56 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"wsaetherfy/currency"
+)
+
+// Chave dos endpoints administrativos; vazia desativa os endpoints
+var adminAPIKey string
+
+// verifyAdminKey valida o header X-Admin-Key.
+func verifyAdminKey(w http.ResponseWriter, r *http.Request) bool {
+	if adminAPIKey == "" {
+		http.Error(w, "Endpoint administrativo desativado", http.StatusNotFound)
+		return false
+	}
+	key := r.Header.Get("X-Admin-Key")
+	if subtle.ConstantTimeCompare([]byte(key), []byte(adminAPIKey)) != 1 {
+		http.Error(w, "Chave administrativa inválida", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// adminSymbolsHandler atende GET /admin/symbols, que lista o catálogo, e
+// POST /admin/symbols com {"pair":"EUR/USD","code":"EURUSD=X","disabled":false},
+// que adiciona, remapeia, ativa ou desativa um par sem reiniciar o serviço.
+func adminSymbolsHandler(w http.ResponseWriter, r *http.Request) {
+	if !verifyAdminKey(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var entry currency.CatalogEntry
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&entry); err != nil {
+			http.Error(w, "Corpo da requisição inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := currency.SetSymbol(entry); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"symbols": currency.CatalogEntries(),
+	})
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"wsaetherfy/alerts"
+	"wsaetherfy/currency"
+	"wsaetherfy/supabase"
+)
+
+// Quantidade máxima de alertas por usuário
+const maxAlertsPerUser = 100
+
+var alertEvaluator *alerts.Evaluator
+
+var errSupabaseNotReady = errors.New("conexão com o Supabase ainda não inicializada")
+
+// supabaseAlertStore guarda os alertas no Supabase usando a conexão global,
+// que é renovada periodicamente.
+type supabaseAlertStore struct{}
+
+func (supabaseAlertStore) List(userID string) ([]alerts.Alert, error) {
+	return supabase.GetAlertsByUserId(supabaseClient, userID)
+}
+
+func (supabaseAlertStore) ListActive() ([]alerts.Alert, error) {
+	if supabaseClient == nil {
+		return nil, errSupabaseNotReady
+	}
+	return supabase.GetActiveAlerts(supabaseClient)
+}
+
+func (supabaseAlertStore) Create(alert alerts.Alert) error {
+	return supabase.InsertAlert(supabaseClient, alert)
+}
+
+func (supabaseAlertStore) Delete(userID, id string) (bool, error) {
+	return supabase.DeleteAlert(supabaseClient, userID, id)
+}
+
+func (supabaseAlertStore) MarkTriggered(id string, at time.Time, active bool) error {
+	return supabase.UpdateAlertTriggered(supabaseClient, id, at, active)
+}
+
+func (supabaseAlertStore) LogDelivery(delivery alerts.Delivery) error {
+	return supabase.InsertAlertDelivery(supabaseClient, delivery)
+}
+
+func (supabaseAlertStore) Deliveries(userID, alertID string) ([]alerts.Delivery, error) {
+	return supabase.GetAlertDeliveries(supabaseClient, userID, alertID)
+}
+
+// alertRequest é o corpo de POST /alerts, por exemplo
+// {"pair":"EUR/USD","condition":"crosses_above","value":1.10,"webhook_url":"https://..."}
+// ou {"pair":"BTC/USD","condition":"change","value":5,"window":"1h","webhook_url":"https://..."}
+type alertRequest struct {
+	Pair       string  `json:"pair"`
+	Condition  string  `json:"condition"`
+	Value      float64 `json:"value"`
+	Window     string  `json:"window"`
+	WebhookURL string  `json:"webhook_url"`
+	Once       bool    `json:"once"`
+}
+
+// alertsHandler atende GET /alerts (lista), POST /alerts (cria) e
+// DELETE /alerts?id= (remove) para o dono da chave API. A gestão dos alertas
+// não consome o limite de chamadas.
+func alertsHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := authorizeSubscriber(w, r)
+	if !ok {
+		return
+	}
+	store := supabaseAlertStore{}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := store.List(userId)
+		if err != nil {
+			log.Printf("Erro ao listar os alertas: %v", err)
+			http.Error(w, "Erro interno do servidor", http.StatusInternalServerError)
+			return
+		}
+		for i := range list {
+			list[i].Secret = ""
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"alerts": list})
+
+	case http.MethodPost:
+		var request alertRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&request); err != nil {
+			http.Error(w, "Corpo da requisição inválido: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, exists := currency.GetCurrencyCode(request.Pair); !exists {
+			http.Error(w, "Par de moedas não encontrado", http.StatusNotFound)
+			return
+		}
+		alert := alerts.Alert{
+			ID:         alerts.NewID(),
+			UserID:     userId,
+			Pair:       request.Pair,
+			Condition:  request.Condition,
+			Value:      request.Value,
+			Window:     request.Window,
+			WebhookURL: request.WebhookURL,
+			Secret:     alerts.NewSecret(),
+			Once:       request.Once,
+			Active:     true,
+			CreatedAt:  time.Now().UTC(),
+		}
+		if err := alert.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		existing, err := store.List(userId)
+		if err != nil {
+			log.Printf("Erro ao listar os alertas: %v", err)
+			http.Error(w, "Erro interno do servidor", http.StatusInternalServerError)
+			return
+		}
+		if len(existing) >= maxAlertsPerUser {
+			http.Error(w, "Limite de alertas atingido", http.StatusConflict)
+			return
+		}
+
+		if err := store.Create(alert); err != nil {
+			log.Printf("Erro ao criar o alerta: %v", err)
+			http.Error(w, "Erro interno do servidor", http.StatusInternalServerError)
+			return
+		}
+		alertEvaluator.Add(alert)
+
+		// O segredo só é retornado aqui, para validar as assinaturas dos webhooks
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(alert)
+
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "Parâmetro id é obrigatório", http.StatusBadRequest)
+			return
+		}
+		deleted, err := store.Delete(userId, id)
+		if err != nil {
+			log.Printf("Erro ao remover o alerta: %v", err)
+			http.Error(w, "Erro interno do servidor", http.StatusInternalServerError)
+			return
+		}
+		// Só remove do avaliador alertas do próprio usuário
+		if !deleted {
+			http.Error(w, "Alerta não encontrado", http.StatusNotFound)
+			return
+		}
+		alertEvaluator.Remove(id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+	}
+}
+
+// alertDeliveriesHandler atende GET /alerts/deliveries?id= com o log de
+// entregas dos webhooks do alerta.
+func alertDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userId, ok := authorizeSubscriber(w, r)
+	if !ok {
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "Parâmetro id é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := supabaseAlertStore{}.Deliveries(userId, id)
+	if err != nil {
+		log.Printf("Erro ao listar as entregas do alerta: %v", err)
+		http.Error(w, "Erro interno do servidor", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"alert_id":   id,
+		"deliveries": deliveries,
+	})
+}
@@ -0,0 +1,129 @@
+package alerts
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Condições aceitas pelos alertas
+const (
+	// O preço passa a ficar acima de Value
+	CrossesAbove = "crosses_above"
+	// O preço passa a ficar abaixo de Value
+	CrossesBelow = "crosses_below"
+	// O preço cruza Value em qualquer sentido
+	Crosses = "crosses"
+	// O preço varia pelo menos Value% dentro de Window
+	Change = "change"
+)
+
+// Alert é um alerta de preço cadastrado por um usuário. Secret assina os
+// webhooks e só é exibido na criação.
+type Alert struct {
+	ID          string     `json:"id"`
+	UserID      string     `json:"user_id"`
+	Pair        string     `json:"pair"`
+	Condition   string     `json:"condition"`
+	Value       float64    `json:"value"`
+	Window      string     `json:"window,omitempty"`
+	WebhookURL  string     `json:"webhook_url"`
+	Secret      string     `json:"secret,omitempty"`
+	Once        bool       `json:"once"`
+	Active      bool       `json:"active"`
+	CreatedAt   time.Time  `json:"created_at"`
+	TriggeredAt *time.Time `json:"triggered_at,omitempty"`
+}
+
+// Validate confere a condição, a janela e o endereço do webhook, que deve ser
+// https e público.
+func (a Alert) Validate() error {
+	switch a.Condition {
+	case CrossesAbove, CrossesBelow, Crosses:
+		if a.Value <= 0 {
+			return fmt.Errorf("value deve ser um preço positivo")
+		}
+	case Change:
+		if a.Value <= 0 {
+			return fmt.Errorf("value deve ser uma variação percentual positiva")
+		}
+		window, err := time.ParseDuration(a.Window)
+		if err != nil || window <= 0 {
+			return fmt.Errorf("window inválida %q, use uma duração como 1h", a.Window)
+		}
+	default:
+		return fmt.Errorf("condição inválida %q, use %s, %s, %s ou %s", a.Condition, CrossesAbove, CrossesBelow, Crosses, Change)
+	}
+
+	u, err := url.Parse(a.WebhookURL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return fmt.Errorf("webhook_url inválida, use um endereço https")
+	}
+	return checkWebhookHost(u.Hostname())
+}
+
+// window retorna a janela dos alertas de variação.
+func (a Alert) window() time.Duration {
+	d, _ := time.ParseDuration(a.Window)
+	return d
+}
+
+// Event é o corpo enviado ao webhook quando um alerta dispara.
+type Event struct {
+	ID            string  `json:"id"`
+	AlertID       string  `json:"alert_id"`
+	Pair          string  `json:"pair"`
+	Condition     string  `json:"condition"`
+	Value         float64 `json:"value"`
+	Price         float64 `json:"price"`
+	ChangePercent float64 `json:"change_percent,omitempty"`
+	// Horário do tick que disparou o alerta, em milissegundos
+	Timestamp   int64 `json:"timestamp"`
+	TriggeredAt int64 `json:"triggered_at"`
+}
+
+// Delivery registra uma tentativa de entrega de um evento ao webhook.
+type Delivery struct {
+	AlertID    string    `json:"alert_id"`
+	UserID     string    `json:"user_id"`
+	EventID    string    `json:"event_id"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Success    bool      `json:"success"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Store persiste os alertas e o log de entregas.
+type Store interface {
+	// List retorna os alertas do usuário.
+	List(userID string) ([]Alert, error)
+	// ListActive retorna os alertas ativos de todos os usuários.
+	ListActive() ([]Alert, error)
+	Create(alert Alert) error
+	// Delete remove o alerta, se ele pertencer ao usuário, e informa se algum
+	// alerta foi removido.
+	Delete(userID, id string) (bool, error)
+	// MarkTriggered registra o disparo e se o alerta continua ativo.
+	MarkTriggered(id string, at time.Time, active bool) error
+	LogDelivery(delivery Delivery) error
+	// Deliveries retorna as entregas do alerta do usuário, das mais recentes
+	// para as mais antigas.
+	Deliveries(userID, alertID string) ([]Delivery, error)
+}
+
+// NewID gera um identificador aleatório para alertas e eventos.
+func NewID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// NewSecret gera o segredo usado para assinar os webhooks de um alerta.
+func NewSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,164 @@
+package alerts
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wsaetherfy/currency"
+)
+
+// memStore registra os disparos e as entregas em memória.
+type memStore struct {
+	sync.Mutex
+	deliveries []Delivery
+	triggered  map[string]bool
+}
+
+func (s *memStore) List(string) ([]Alert, error)                  { return nil, nil }
+func (s *memStore) ListActive() ([]Alert, error)                  { return nil, nil }
+func (s *memStore) Create(Alert) error                            { return nil }
+func (s *memStore) Delete(string, string) (bool, error)           { return false, nil }
+func (s *memStore) Deliveries(string, string) ([]Delivery, error) { return nil, nil }
+
+func (s *memStore) MarkTriggered(id string, at time.Time, active bool) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.triggered == nil {
+		s.triggered = make(map[string]bool)
+	}
+	s.triggered[id] = active
+	return nil
+}
+
+func (s *memStore) LogDelivery(d Delivery) error {
+	s.Lock()
+	defer s.Unlock()
+	s.deliveries = append(s.deliveries, d)
+	return nil
+}
+
+func TestEvaluateCrossing(t *testing.T) {
+	e := NewEvaluator(&memStore{}, nil)
+	e.Add(Alert{ID: "above", Pair: "EUR/USD", Condition: CrossesAbove, Value: 1.10, Active: true})
+	e.Add(Alert{ID: "once", Pair: "EUR/USD", Condition: Crosses, Value: 1.10, Active: true, Once: true})
+
+	prices := []float64{1.09, 1.11, 1.12, 1.08, 1.10}
+	var fired []string
+	for i, price := range prices {
+		for _, f := range e.evaluate("EUR/USD", currency.PriceData{Price: price, Timestamp: int64(i)}) {
+			fired = append(fired, f.alert.ID+"@"+strconv.FormatFloat(price, 'f', 2, 64))
+		}
+	}
+
+	want := map[string]bool{"above@1.11": true, "once@1.11": true, "above@1.10": true}
+	if len(fired) != len(want) {
+		t.Fatalf("fired %v, want %v", fired, want)
+	}
+	for _, f := range fired {
+		if !want[f] {
+			t.Fatalf("unexpected fire %s (all: %v)", f, fired)
+		}
+	}
+}
+
+func TestDispatcherRetriesAndSigns(t *testing.T) {
+	var calls int32
+	var signatureOK atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		signatureOK.Store(r.Header.Get(SignatureHeader) == Sign("secret", timestamp, body))
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	store := &memStore{}
+	d := &Dispatcher{store: store, client: server.Client(), maxAttempts: 5, backoff: time.Millisecond}
+	alert := Alert{ID: "a1", UserID: "u1", WebhookURL: server.URL, Secret: "secret"}
+	d.deliver(alert, Event{ID: "e1", AlertID: "a1", Price: 1.1})
+
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+	if !signatureOK.Load() {
+		t.Fatal("invalid webhook signature")
+	}
+	if len(store.deliveries) != 3 || store.deliveries[0].Success || !store.deliveries[2].Success || store.deliveries[2].Attempt != 3 {
+		t.Fatalf("unexpected delivery log %+v", store.deliveries)
+	}
+}
+
+func TestDispatcherDoesNotRetryClientErrors(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	store := &memStore{}
+	d := &Dispatcher{store: store, client: server.Client(), maxAttempts: 5, backoff: time.Millisecond}
+	d.deliver(Alert{ID: "a1", WebhookURL: server.URL}, Event{ID: "e1"})
+
+	if calls != 1 || len(store.deliveries) != 1 || store.deliveries[0].StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a single failed attempt, got %d calls and %+v", calls, store.deliveries)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	defer func(original func(context.Context, string) ([]net.IP, error)) { lookupIP = original }(lookupIP)
+	lookupIP = func(_ context.Context, host string) ([]net.IP, error) {
+		if host == "internal.example.com" {
+			return []net.IP{net.ParseIP("10.0.0.5")}, nil
+		}
+		return []net.IP{net.ParseIP("93.184.216.34")}, nil
+	}
+
+	valid := Alert{Condition: Change, Value: 5, Window: "1h", WebhookURL: "https://example.com/hook"}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, alert := range []Alert{
+		{Condition: "above", Value: 1, WebhookURL: "https://example.com"},
+		{Condition: Change, Value: 5, WebhookURL: "https://example.com"},
+		{Condition: Crosses, Value: 1, WebhookURL: "ftp://example.com"},
+		{Condition: Crosses, Value: 1, WebhookURL: "http://example.com"},
+		{Condition: Crosses, Value: 1, WebhookURL: "https://localhost/hook"},
+		{Condition: Crosses, Value: 1, WebhookURL: "https://127.0.0.1/hook"},
+		{Condition: Crosses, Value: 1, WebhookURL: "https://169.254.169.254/latest/meta-data"},
+		{Condition: Crosses, Value: 1, WebhookURL: "https://[::1]/hook"},
+		{Condition: Crosses, Value: 1, WebhookURL: "https://internal.example.com/hook"},
+	} {
+		if err := alert.Validate(); err == nil {
+			t.Errorf("expected error for %+v", alert)
+		}
+	}
+}
+
+func TestWebhookClientRefusesPrivateAddresses(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer server.Close()
+
+	resp, err := newWebhookClient(time.Second).Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected connection to loopback to be refused")
+	}
+	if calls != 0 {
+		t.Fatalf("server received %d requests", calls)
+	}
+}
@@ -0,0 +1,214 @@
+package alerts
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+	"wsaetherfy/currency"
+)
+
+// Intervalo entre as tentativas de carregar os alertas na inicialização
+const loadRetryInterval = 10 * time.Second
+
+// Notifier recebe os alertas disparados pelo avaliador.
+type Notifier interface {
+	Notify(alert Alert, event Event)
+}
+
+// alertState guarda o alerta e o último disparo, usado no intervalo mínimo
+// entre disparos dos alertas de variação.
+type alertState struct {
+	Alert
+	lastFired int64
+}
+
+// Evaluator assina no hub os pares com alertas ativos e avalia cada tick.
+// Os disparos são repassados ao Notifier sem bloquear a leitura dos ticks.
+type Evaluator struct {
+	mu       sync.Mutex
+	store    Store
+	notifier Notifier
+	alerts   map[string]map[string]*alertState // par -> id -> alerta
+	last     map[string]float64                // par -> último preço
+	sub      *currency.Subscriber
+}
+
+func NewEvaluator(store Store, notifier Notifier) *Evaluator {
+	return &Evaluator{
+		store:    store,
+		notifier: notifier,
+		alerts:   make(map[string]map[string]*alertState),
+		last:     make(map[string]float64),
+	}
+}
+
+// Run carrega os alertas ativos e avalia os ticks até ctx ser cancelado. Se o
+// avaliador for removido do hub por lentidão, uma nova assinatura é feita.
+func (e *Evaluator) Run(ctx context.Context) {
+	for {
+		list, err := e.store.ListActive()
+		if err == nil {
+			for _, alert := range list {
+				e.Add(alert)
+			}
+			log.Printf("Alertas carregados: %d", len(list))
+			break
+		}
+		log.Printf("Erro ao carregar os alertas: %v", err)
+		select {
+		case <-time.After(loadRetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	for {
+		sub := e.subscribe()
+		e.consume(ctx, sub)
+		sub.Close()
+		if ctx.Err() != nil {
+			return
+		}
+		log.Println("Avaliador de alertas removido do hub, assinando novamente")
+	}
+}
+
+// subscribe cria um novo assinante com todos os pares que têm alertas.
+func (e *Evaluator) subscribe() *currency.Subscriber {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sub = currency.NewSubscriber()
+	pairs := make([]string, 0, len(e.alerts))
+	for pair := range e.alerts {
+		pairs = append(pairs, pair)
+	}
+	e.sub.Subscribe(pairs...)
+	return e.sub
+}
+
+func (e *Evaluator) consume(ctx context.Context, sub *currency.Subscriber) {
+	for {
+		select {
+		case update, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if update.Candle != nil || update.Cross != nil {
+				continue
+			}
+			for _, fired := range e.evaluate(update.Pair, update.Data) {
+				e.notifier.Notify(fired.alert, fired.event)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Add passa a avaliar o alerta.
+func (e *Evaluator) Add(alert Alert) {
+	if !alert.Active {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.alerts[alert.Pair] == nil {
+		e.alerts[alert.Pair] = make(map[string]*alertState)
+		if e.sub != nil {
+			e.sub.Subscribe(alert.Pair)
+		}
+	}
+	e.alerts[alert.Pair][alert.ID] = &alertState{Alert: alert}
+}
+
+// Remove deixa de avaliar o alerta.
+func (e *Evaluator) Remove(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.remove(id)
+}
+
+func (e *Evaluator) remove(id string) {
+	for pair, byID := range e.alerts {
+		if _, found := byID[id]; !found {
+			continue
+		}
+		delete(byID, id)
+		if len(byID) == 0 {
+			delete(e.alerts, pair)
+			delete(e.last, pair)
+			if e.sub != nil {
+				e.sub.Unsubscribe(pair)
+			}
+		}
+		return
+	}
+}
+
+type firedAlert struct {
+	alert Alert
+	event Event
+}
+
+// evaluate confere os alertas do par com o novo tick.
+func (e *Evaluator) evaluate(pair string, data currency.PriceData) []firedAlert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	prev, hasPrev := e.last[pair]
+	e.last[pair] = data.Price
+
+	var fired []firedAlert
+	for _, state := range e.alerts[pair] {
+		event := Event{
+			ID:          NewID(),
+			AlertID:     state.ID,
+			Pair:        pair,
+			Condition:   state.Condition,
+			Value:       state.Value,
+			Price:       data.Price,
+			Timestamp:   data.Timestamp,
+			TriggeredAt: time.Now().UnixMilli(),
+		}
+
+		switch state.Condition {
+		case CrossesAbove, CrossesBelow, Crosses:
+			if !hasPrev {
+				continue
+			}
+			above := prev < state.Value && data.Price >= state.Value
+			below := prev > state.Value && data.Price <= state.Value
+			if !(state.Condition == CrossesAbove && above ||
+				state.Condition == CrossesBelow && below ||
+				state.Condition == Crosses && (above || below)) {
+				continue
+			}
+		case Change:
+			window := state.window().Milliseconds()
+			if state.lastFired > 0 && data.Timestamp-state.lastFired < window {
+				continue
+			}
+			page, found := currency.GetHistory(pair, data.Timestamp-window, data.Timestamp, 0, 1)
+			if !found || len(page.Ticks) == 0 || page.Ticks[0].Price == 0 {
+				continue
+			}
+			change := (data.Price - page.Ticks[0].Price) / page.Ticks[0].Price * 100
+			if math.Abs(change) < state.Value {
+				continue
+			}
+			event.ChangePercent = change
+		default:
+			continue
+		}
+
+		state.lastFired = data.Timestamp
+		alert := state.Alert
+		if alert.Once {
+			alert.Active = false
+			e.remove(alert.ID)
+		}
+		fired = append(fired, firedAlert{alert: alert, event: event})
+	}
+	return fired
+}
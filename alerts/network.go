@@ -0,0 +1,108 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Faixas que não são endereços públicos mas não são reconhecidas pelos
+// métodos de net.IP
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// lookupIP resolve o host dos webhooks; substituído nos testes.
+var lookupIP = func(ctx context.Context, host string) ([]net.IP, error) {
+	return net.DefaultResolver.LookupIP(ctx, "ip", host)
+}
+
+// publicIP informa se o endereço pode receber webhooks. Loopback, redes
+// privadas, link-local (como o 169.254.169.254 dos metadados de nuvem) e
+// demais faixas reservadas são recusados.
+func publicIP(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkWebhookHost recusa hosts locais ou que resolvem para endereços não
+// públicos.
+func checkWebhookHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("webhook_url não pode apontar para um endereço local")
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !publicIP(ip) {
+			return fmt.Errorf("webhook_url não pode apontar para um endereço privado")
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ips, err := lookupIP(ctx, host)
+	if err != nil || len(ips) == 0 {
+		return fmt.Errorf("não foi possível resolver o host da webhook_url")
+	}
+	for _, ip := range ips {
+		if !publicIP(ip) {
+			return fmt.Errorf("webhook_url não pode apontar para um endereço privado")
+		}
+	}
+	return nil
+}
+
+// safeControl confere o endereço já resolvido de cada conexão, para que um
+// DNS que mude depois da validação (DNS rebinding) ou um redirecionamento não
+// alcancem a rede interna.
+func safeControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicIP(net.ParseIP(host)) {
+		return fmt.Errorf("conexão com endereço não público %s recusada", host)
+	}
+	return nil
+}
+
+// newWebhookClient cria o cliente HTTP dos webhooks, que só conecta em
+// endereços públicos e ignora proxies do ambiente.
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: safeControl}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
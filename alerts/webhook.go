@@ -0,0 +1,151 @@
+package alerts
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers enviados em cada webhook. A assinatura é o HMAC-SHA256, com o
+// segredo do alerta, de "<timestamp>.<corpo>".
+const (
+	SignatureHeader = "X-Aetherfy-Signature"
+	TimestampHeader = "X-Aetherfy-Timestamp"
+	EventHeader     = "X-Aetherfy-Event"
+)
+
+// Tamanho da fila de eventos aguardando entrega
+const queueSize = 1024
+
+// Sign calcula a assinatura do corpo do webhook no formato "sha256=<hex>".
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type job struct {
+	alert Alert
+	event Event
+}
+
+// Dispatcher entrega os eventos aos webhooks em segundo plano, tentando de
+// novo com espera exponencial quando o destino falha, e registra cada
+// tentativa no log de entregas.
+type Dispatcher struct {
+	store       Store
+	client      *http.Client
+	queue       chan job
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// NewDispatcher inicia workers goroutines de entrega. Cada evento é tentado
+// até maxAttempts vezes, com timeout de entrega por tentativa.
+func NewDispatcher(store Store, workers, maxAttempts int, timeout time.Duration) *Dispatcher {
+	d := &Dispatcher{
+		store:       store,
+		client:      newWebhookClient(timeout),
+		queue:       make(chan job, queueSize),
+		maxAttempts: maxAttempts,
+		backoff:     time.Second,
+	}
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+// Notify coloca o evento na fila de entrega sem bloquear. Com a fila cheia,
+// o evento é descartado.
+func (d *Dispatcher) Notify(alert Alert, event Event) {
+	select {
+	case d.queue <- job{alert: alert, event: event}:
+	default:
+		log.Printf("Fila de webhooks cheia, evento %s do alerta %s descartado", event.ID, alert.ID)
+	}
+}
+
+func (d *Dispatcher) work() {
+	for j := range d.queue {
+		if err := d.store.MarkTriggered(j.alert.ID, time.UnixMilli(j.event.TriggeredAt), j.alert.Active); err != nil {
+			log.Printf("Erro ao registrar o disparo do alerta %s: %v", j.alert.ID, err)
+		}
+		d.deliver(j.alert, j.event)
+	}
+}
+
+// deliver envia o evento, tentando de novo em erros de rede, 429 e 5xx.
+func (d *Dispatcher) deliver(alert Alert, event Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Erro ao codificar o evento %s: %v", event.ID, err)
+		return
+	}
+
+	wait := d.backoff
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		status, err := d.post(alert, event, body)
+		delivery := Delivery{
+			AlertID:    alert.ID,
+			UserID:     alert.UserID,
+			EventID:    event.ID,
+			Attempt:    attempt,
+			StatusCode: status,
+			Success:    err == nil,
+			CreatedAt:  time.Now().UTC(),
+		}
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if logErr := d.store.LogDelivery(delivery); logErr != nil {
+			log.Printf("Erro ao registrar a entrega do evento %s: %v", event.ID, logErr)
+		}
+
+		if err == nil || !retryable(status) {
+			return
+		}
+		if attempt < d.maxAttempts {
+			time.Sleep(wait)
+			wait *= 2
+		}
+	}
+	log.Printf("Webhook do alerta %s falhou após %d tentativas", alert.ID, d.maxAttempts)
+}
+
+func (d *Dispatcher) post(alert Alert, event Event, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, alert.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(alert.Secret, timestamp, body))
+	req.Header.Set(EventHeader, event.ID)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook respondeu %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable informa se a entrega deve ser repetida. Erros de rede não têm
+// status.
+func retryable(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status >= 500
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"wsaetherfy/supabase"
+)
+
+// apiCaller identifica o usuário dono da chave API e o seu consumo atual.
+type apiCaller struct {
+	userId      string
+	apiCalls    int
+	maxApiCalls int
+}
+
+// verifyAPIKey valida o header X-API-Key, respondendo com erro quando a
+// chave está ausente ou é inválida.
+func verifyAPIKey(w http.ResponseWriter, r *http.Request) (string, bool) {
+	apiKey := r.Header.Get("X-API-Key")
+	if apiKey == "" {
+		http.Error(w, "API key is required", http.StatusUnauthorized)
+		return "", false
+	}
+
+	valid, err := supabase.VerifyAPIKey(supabaseClient, apiKey)
+	if err != nil {
+		log.Println("Erro ao verificar a chave API:", err)
+		http.Error(w, "Erro interno do servidor", http.StatusInternalServerError)
+		return "", false
+	}
+	if !valid {
+		http.Error(w, "Chave API inválida", http.StatusUnauthorized)
+		return "", false
+	}
+	return apiKey, true
+}
+
+// authorizeSubscriber valida a chave API e a assinatura do usuário, sem
+// consultar o limite de chamadas, e retorna o id do usuário.
+func authorizeSubscriber(w http.ResponseWriter, r *http.Request) (string, bool) {
+	apiKey, ok := verifyAPIKey(w, r)
+	if !ok {
+		return "", false
+	}
+
+	userId, err := supabase.GetUserIdByApiKey(supabaseClient, apiKey)
+	if err != nil {
+		log.Printf("Error getting user ID by API key: %v", err)
+		http.Error(w, "Error getting user ID by API key", http.StatusInternalServerError)
+		return "", false
+	}
+
+	subscriptionStatus, err := supabase.GetSubscriptionStatus(supabaseClient, userId)
+	if err != nil {
+		log.Printf("Error getting subscription status: %v", err)
+		http.Error(w, "Error getting subscription status", http.StatusInternalServerError)
+		return "", false
+	}
+
+	if subscriptionStatus != "active" {
+		http.Error(w, "Subscription is not active", http.StatusUnauthorized)
+		return "", false
+	}
+
+	return userId, true
+}
+
+// authorizeAPICall valida a chave API, a assinatura e o limite de chamadas do
+// usuário. As chamadas só são contabilizadas quando charge é invocado.
+func authorizeAPICall(w http.ResponseWriter, r *http.Request) (*apiCaller, bool) {
+	userId, ok := authorizeSubscriber(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	maxApiCalls, apiCalls, err := supabase.GetApiUsageByUserId(supabaseClient, userId)
+	if err != nil {
+		log.Printf("Error getting API usage by user ID: %v", err)
+		http.Error(w, "Error getting API usage by user ID", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if apiCalls >= maxApiCalls {
+		http.Error(w, "API usage limit exceeded", http.StatusTooManyRequests)
+		return nil, false
+	}
+
+	return &apiCaller{userId: userId, apiCalls: apiCalls, maxApiCalls: maxApiCalls}, true
+}
+
+// remaining retorna quantas chamadas o usuário ainda pode fazer.
+func (c *apiCaller) remaining() int {
+	return c.maxApiCalls - c.apiCalls
+}
+
+// charge contabiliza as chamadas feitas pelo usuário.
+func (c *apiCaller) charge(calls int) {
+	err := supabase.UpdateApiUserUsage(supabaseClient, c.userId, c.apiCalls+calls)
+	if err != nil {
+		log.Printf("Error updating API user usage: %v", err)
+	}
+}
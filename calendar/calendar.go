@@ -0,0 +1,244 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	// Embute o banco de fusos para não depender do zoneinfo do sistema
+	_ "time/tzdata"
+)
+
+// Session é a fase do pregão, com os mesmos nomes do MarketHours do Yaticker.
+type Session string
+
+const (
+	Closed  Session = "CLOSED"
+	Pre     Session = "PRE_MARKET"
+	Regular Session = "REGULAR_MARKET"
+	Post    Session = "POST_MARKET"
+)
+
+// Motivos de um mercado fechado
+const (
+	ReasonWeekend    = "weekend"
+	ReasonHoliday    = "holiday"
+	ReasonAfterHours = "after_hours"
+)
+
+// Granularidade usada para procurar a próxima abertura e o último fechamento.
+// Os horários de todos os calendários caem em múltiplos dela.
+const step = 15 * time.Minute
+
+// Limite da busca por abertura e fechamento
+const searchLimit = 14 * 24 * time.Hour
+
+// Status é a situação do mercado em um instante.
+type Status struct {
+	Session Session
+	// Open indica o pregão regular
+	Open    bool
+	Reason  string
+	Holiday string
+}
+
+// Calendar conhece as sessões semanais e os feriados de um mercado.
+type Calendar struct {
+	Name     string
+	location *time.Location
+	// session retorna a fase do pregão no horário local, sem feriados
+	session func(local time.Time) (Session, string)
+	// rules calcula os feriados recorrentes de um ano
+	rules    func(year int) map[string]string
+	mu       sync.RWMutex
+	holidays map[string]string // "2006-01-02" -> nome
+	// Anos cujos feriados recorrentes já foram incluídos em holidays
+	years map[int]bool
+}
+
+func newCalendar(name, zone string, session func(time.Time) (Session, string), rules func(int) map[string]string) *Calendar {
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		panic(fmt.Sprintf("calendar: fuso %s indisponível: %v", zone, err))
+	}
+	return &Calendar{
+		Name:     name,
+		location: location,
+		session:  session,
+		rules:    rules,
+		holidays: make(map[string]string),
+		years:    make(map[int]bool),
+	}
+}
+
+// holiday retorna o feriado da data local, calculando na primeira consulta
+// os feriados recorrentes do ano.
+func (c *Calendar) holiday(local time.Time) (string, bool) {
+	date := local.Format(time.DateOnly)
+	c.mu.RLock()
+	name, found := c.holidays[date]
+	loaded := c.years[local.Year()]
+	c.mu.RUnlock()
+	if found || loaded || c.rules == nil {
+		return name, found
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.years[local.Year()] {
+		for day, name := range c.rules(local.Year()) {
+			// Feriados registrados por AddHoliday têm prioridade
+			if _, exists := c.holidays[day]; !exists {
+				c.holidays[day] = name
+			}
+		}
+		c.years[local.Year()] = true
+	}
+	name, found = c.holidays[date]
+	return name, found
+}
+
+// AddHoliday fecha o mercado no dia informado ("2006-01-02").
+func (c *Calendar) AddHoliday(date, name string) error {
+	if _, err := time.Parse(time.DateOnly, date); err != nil {
+		return fmt.Errorf("data inválida %q, use AAAA-MM-DD", date)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.holidays[date] = name
+	return nil
+}
+
+// At retorna a situação do mercado no instante t.
+func (c *Calendar) At(t time.Time) Status {
+	local := t.In(c.location)
+	if holiday, found := c.holiday(local); found {
+		return Status{Session: Closed, Reason: ReasonHoliday, Holiday: holiday}
+	}
+	session, reason := c.session(local)
+	return Status{Session: session, Open: session == Regular, Reason: reason}
+}
+
+// NextOpen retorna quando o pregão regular abre a partir de t. Se o mercado
+// está aberto, retorna t.
+func (c *Calendar) NextOpen(t time.Time) (time.Time, bool) {
+	if c.At(t).Open {
+		return t, true
+	}
+	for at := t.Truncate(step).Add(step); at.Sub(t) <= searchLimit; at = at.Add(step) {
+		if c.At(at).Open {
+			return at, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// LastClose retorna quando o último pregão regular anterior a t terminou.
+// Retorna falso se o mercado está aberto em t.
+func (c *Calendar) LastClose(t time.Time) (time.Time, bool) {
+	if c.At(t).Open {
+		return time.Time{}, false
+	}
+	for at := t.Truncate(step); t.Sub(at) <= searchLimit; at = at.Add(-step) {
+		if at.Before(t) && c.At(at).Open {
+			return at.Add(step), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Crypto negocia o tempo todo.
+var Crypto = newCalendar("crypto", "UTC", func(time.Time) (Session, string) {
+	return Regular, ""
+}, nil)
+
+// FX abre no domingo às 17h de Nova York e fecha na sexta às 17h.
+var FX = newCalendar("fx", "America/New_York", func(local time.Time) (Session, string) {
+	switch local.Weekday() {
+	case time.Saturday:
+		return Closed, ReasonWeekend
+	case time.Friday:
+		if local.Hour() >= 17 {
+			return Closed, ReasonWeekend
+		}
+	case time.Sunday:
+		if local.Hour() < 17 {
+			return Closed, ReasonWeekend
+		}
+	}
+	return Regular, ""
+}, nil)
+
+// US cobre as bolsas americanas: pré-mercado das 4h às 9h30, pregão regular
+// até as 16h e pós-mercado até as 20h, no horário de Nova York, fechando nos
+// feriados da NYSE.
+var US = newCalendar("us", "America/New_York", func(local time.Time) (Session, string) {
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		return Closed, ReasonWeekend
+	}
+	minute := local.Hour()*60 + local.Minute()
+	switch {
+	case minute < 4*60:
+		return Closed, ReasonAfterHours
+	case minute < 9*60+30:
+		return Pre, ""
+	case minute < 16*60:
+		return Regular, ""
+	case minute < 20*60:
+		return Post, ""
+	}
+	return Closed, ReasonAfterHours
+}, usHolidays)
+
+// Códigos de bolsa do Yahoo atendidos por cada calendário
+var exchanges = map[string]*Calendar{
+	"NYQ": US, "NMS": US, "NGM": US, "NCM": US, "NIM": US, "ASE": US,
+	"PCX": US, "BTS": US, "NYSE": US, "NASDAQ": US, "SNP": US, "DJI": US,
+	"WCB": US, "CBO": US, "OPR": US,
+}
+
+// ByName retorna o calendário pelo nome (crypto, fx, us).
+func ByName(name string) (*Calendar, bool) {
+	for _, c := range []*Calendar{Crypto, FX, US} {
+		if c.Name == strings.ToLower(name) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// For escolhe o calendário de um instrumento pela classe de ativo e pela
+// bolsa. Retorna falso quando a bolsa não é conhecida.
+func For(assetClass, exchange string) (*Calendar, bool) {
+	switch assetClass {
+	case "fx":
+		return FX, true
+	case "crypto":
+		return Crypto, true
+	}
+	c, found := exchanges[strings.ToUpper(exchange)]
+	return c, found
+}
+
+// AddHolidays registra feriados extras no formato
+// "calendário:AAAA-MM-DD,calendário:AAAA-MM-DD", ex.: "us:2026-12-24".
+func AddHolidays(spec string) error {
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, date, ok := strings.Cut(item, ":")
+		if !ok {
+			return fmt.Errorf("feriado inválido %q, use calendário:AAAA-MM-DD", item)
+		}
+		c, found := ByName(name)
+		if !found {
+			return fmt.Errorf("calendário desconhecido: %s", name)
+		}
+		if err := c.AddHoliday(date, "holiday"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func ny(t *testing.T, value string) time.Time {
+	t.Helper()
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at, err := time.ParseInLocation("2006-01-02 15:04", value, location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return at
+}
+
+func TestFXWeekend(t *testing.T) {
+	tests := []struct {
+		at   string
+		open bool
+	}{
+		{"2026-10-16 16:59", true},  // sexta
+		{"2026-10-16 17:00", false}, // sexta, fechamento
+		{"2026-10-17 12:00", false}, // sábado
+		{"2026-10-18 16:45", false}, // domingo
+		{"2026-10-18 17:00", true},  // domingo, abertura
+	}
+	for _, tt := range tests {
+		if status := FX.At(ny(t, tt.at)); status.Open != tt.open {
+			t.Errorf("FX.At(%s) = %+v, want open %v", tt.at, status, tt.open)
+		}
+	}
+
+	saturday := ny(t, "2026-10-17 12:00")
+	if status := FX.At(saturday); status.Reason != ReasonWeekend {
+		t.Errorf("reason = %q, want %q", status.Reason, ReasonWeekend)
+	}
+	if closed, ok := FX.LastClose(saturday); !ok || !closed.Equal(ny(t, "2026-10-16 17:00")) {
+		t.Errorf("LastClose = %v, %v", closed, ok)
+	}
+	if open, ok := FX.NextOpen(saturday); !ok || !open.Equal(ny(t, "2026-10-18 17:00")) {
+		t.Errorf("NextOpen = %v, %v", open, ok)
+	}
+}
+
+func TestUSSessionsAndHolidays(t *testing.T) {
+	tests := []struct {
+		at      string
+		session Session
+	}{
+		{"2026-10-16 03:00", Closed},
+		{"2026-10-16 08:00", Pre},
+		{"2026-10-16 09:30", Regular},
+		{"2026-10-16 16:00", Post},
+		{"2026-10-16 20:00", Closed},
+		{"2026-11-26 12:00", Closed}, // Thanksgiving
+	}
+	for _, tt := range tests {
+		if status := US.At(ny(t, tt.at)); status.Session != tt.session {
+			t.Errorf("US.At(%s) = %+v, want %s", tt.at, status, tt.session)
+		}
+	}
+
+	thanksgiving := ny(t, "2026-11-26 12:00")
+	if status := US.At(thanksgiving); status.Reason != ReasonHoliday || status.Holiday == "" {
+		t.Errorf("expected holiday, got %+v", US.At(thanksgiving))
+	}
+	if closed, ok := US.LastClose(thanksgiving); !ok || !closed.Equal(ny(t, "2026-11-25 16:00")) {
+		t.Errorf("LastClose = %v, %v", closed, ok)
+	}
+	if open, ok := US.NextOpen(thanksgiving); !ok || !open.Equal(ny(t, "2026-11-27 09:30")) {
+		t.Errorf("NextOpen = %v, %v", open, ok)
+	}
+	if _, ok := US.LastClose(ny(t, "2026-10-16 12:00")); ok {
+		t.Error("LastClose during regular session should report open market")
+	}
+}
+
+func TestUSHolidayRules(t *testing.T) {
+	want := []string{
+		"2025-01-01", "2025-01-20", "2025-02-17", "2025-04-18", "2025-05-26",
+		"2025-06-19", "2025-07-04", "2025-09-01", "2025-11-27", "2025-12-25",
+		"2026-01-01", "2026-01-19", "2026-02-16", "2026-04-03", "2026-05-25",
+		"2026-06-19", "2026-07-03", "2026-09-07", "2026-11-26", "2026-12-25",
+		"2027-01-01", "2027-01-18", "2027-02-15", "2027-03-26", "2027-05-31",
+		"2027-06-18", "2027-07-05", "2027-09-06", "2027-11-25", "2027-12-24",
+	}
+	got := make(map[string]string)
+	for year := 2025; year <= 2027; year++ {
+		for date, name := range usHolidays(year) {
+			got[date] = name
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d holidays, want %d: %v", len(got), len(want), got)
+	}
+	for _, date := range want {
+		if _, found := got[date]; !found {
+			t.Errorf("missing holiday %s", date)
+		}
+	}
+
+	// Ano-Novo no sábado não é observado na sexta anterior
+	if _, found := usHolidays(2021)["2021-12-31"]; found {
+		t.Error("2021-12-31 should not be a holiday")
+	}
+	if status := US.At(ny(t, "2031-11-27 12:00")); status.Reason != ReasonHoliday {
+		t.Errorf("expected Thanksgiving 2031 to be a holiday, got %+v", status)
+	}
+}
@@ -0,0 +1,74 @@
+package calendar
+
+import "time"
+
+// usHolidays calcula os feriados da NYSE no ano. Feriados de data fixa no
+// sábado são observados na sexta anterior e, no domingo, na segunda seguinte;
+// a exceção é o Ano-Novo no sábado, que não fecha a bolsa em 31 de dezembro.
+func usHolidays(year int) map[string]string {
+	holidays := make(map[string]string)
+	add := func(date time.Time, name string) {
+		holidays[date.Format(time.DateOnly)] = name
+	}
+	fixed := func(month time.Month, day int, name string) {
+		date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		switch date.Weekday() {
+		case time.Saturday:
+			add(date.AddDate(0, 0, -1), name+" (observed)")
+		case time.Sunday:
+			add(date.AddDate(0, 0, 1), name+" (observed)")
+		default:
+			add(date, name)
+		}
+	}
+
+	if newYear := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC); newYear.Weekday() != time.Saturday {
+		fixed(time.January, 1, "New Year's Day")
+	}
+	add(nthWeekday(year, time.January, time.Monday, 3), "Martin Luther King Jr. Day")
+	add(nthWeekday(year, time.February, time.Monday, 3), "Washington's Birthday")
+	add(easter(year).AddDate(0, 0, -2), "Good Friday")
+	add(lastWeekday(year, time.May, time.Monday), "Memorial Day")
+	if year >= 2022 {
+		fixed(time.June, 19, "Juneteenth")
+	}
+	fixed(time.July, 4, "Independence Day")
+	add(nthWeekday(year, time.September, time.Monday, 1), "Labor Day")
+	add(nthWeekday(year, time.November, time.Thursday, 4), "Thanksgiving Day")
+	fixed(time.December, 25, "Christmas Day")
+	return holidays
+}
+
+// nthWeekday retorna o n-ésimo dia da semana do mês.
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+7*(n-1))
+}
+
+// lastWeekday retorna o último dia da semana do mês.
+func lastWeekday(year int, month time.Month, weekday time.Weekday) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	offset := (int(last.Weekday()) - int(weekday) + 7) % 7
+	return last.AddDate(0, 0, -offset)
+}
+
+// easter retorna o domingo de Páscoa do calendário gregoriano (algoritmo de
+// Meeus/Jones/Butcher).
+func easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"wsaetherfy/currency"
+)
+
+const (
+	defaultCandleLimit = 200
+	maxCandleLimit     = 1000
+)
+
+type candleJSON struct {
+	Start  int64   `json:"start"`
+	Open   float64 `json:"open"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	Volume int64   `json:"volume"`
+	Ticks  int     `json:"ticks"`
+}
+
+func toCandleJSON(c currency.Candle) candleJSON {
+	return candleJSON{
+		Start:  c.Start,
+		Open:   c.Open,
+		High:   c.High,
+		Low:    c.Low,
+		Close:  c.Close,
+		Volume: c.Volume,
+		Ticks:  c.Ticks,
+	}
+}
+
+// Mensagem enviada no canal de candles do /ws
+func wsCandleMessage(pair string, update currency.CandleUpdate) map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "candle",
+		"pair":     pair,
+		"interval": update.Interval,
+		"candle":   toCandleJSON(update.Candle),
+	}
+}
+
+// candlesHandler atende GET /candles?pair=&interval=&limit=
+func candlesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	caller, ok := authorizeAPICall(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	pair := query.Get("pair")
+	if pair == "" {
+		http.Error(w, "Par de moedas é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	interval := query.Get("interval")
+	if !currency.IsCandleInterval(interval) {
+		http.Error(w, "Intervalo inválido, use "+strings.Join(currency.CandleIntervals(), ", "), http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultCandleLimit
+	if value := query.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			http.Error(w, "Parâmetro limit inválido", http.StatusBadRequest)
+			return
+		}
+		if limit > maxCandleLimit {
+			limit = maxCandleLimit
+		}
+	}
+
+	list, found := currency.GetCandles(pair, interval, limit)
+	if !found {
+		http.Error(w, "Par de moedas não encontrado", http.StatusNotFound)
+		return
+	}
+
+	result := make([]candleJSON, 0, len(list))
+	for _, c := range list {
+		result = append(result, toCandleJSON(c))
+	}
+	response := map[string]interface{}{
+		"pair":     pair,
+		"interval": interval,
+		"candles":  result,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+	caller.charge(1)
+}
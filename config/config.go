@@ -0,0 +1,180 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"wsaetherfy/currency"
+	"wsaetherfy/websocket"
+
+	"github.com/joho/godotenv"
+)
+
+// Config reúne as configurações do serviço lidas do ambiente (ou do .env).
+type Config struct {
+	// Quantidade máxima de ticks guardados por par
+	PriceHistoryMaxPoints int
+	// Idade máxima dos ticks guardados por par; zero desativa o limite
+	PriceHistoryMaxAge time.Duration
+	// Quantidade máxima de candles fechados guardados por par e intervalo
+	CandleHistoryMax int
+	// Diretório dos segmentos de ticks persistidos; vazio desativa a persistência
+	TickStoreDir string
+	// Por quanto tempo os segmentos de ticks são mantidos em disco
+	TickStoreRetention time.Duration
+	// Endereço do streamer do Yahoo; vazio usa o streamer real
+	YahooURL string
+	// Política de reconexão com o streamer do Yahoo
+	YahooReconnect websocket.ReconnectPolicy
+	// Intervalo dos pings e tempo máximo sem frames do streamer do Yahoo
+	YahooPingInterval time.Duration
+	YahooReadTimeout  time.Duration
+	// Tempo sem ticks após o qual a cotação de um par é marcada como parada
+	QuoteStaleAfter time.Duration
+	// Arquivo onde os frames brutos do Yahoo são gravados; vazio desativa
+	RecordFile string
+	// Gravação reproduzida no lugar do streamer do Yahoo; vazio desativa
+	ReplayFile string
+	// Velocidade da reprodução (1 é tempo real, 0 sem pausas)
+	ReplaySpeed float64
+	// Recomeça a reprodução ao chegar ao fim da gravação
+	ReplayLoop bool
+	// Provedor JSON-over-WebSocket alternativo; desativado quando a URL é vazia
+	JSONFeed websocket.JSONFeedConfig
+	// Rotas por par no formato "provedor:símbolo", ex.: BTC/USD=jsonfeed:BTCUSDT
+	PairProviders map[string]string
+	// Provedores alternativos por par, no mesmo formato de PairProviders
+	PairFallbacks map[string]string
+	// Tempo sem ticks após o qual o provedor principal de um par é trocado
+	FailoverStaleAfter time.Duration
+	// Arquivo JSON do catálogo de símbolos; vazio usa o catálogo padrão
+	CatalogFile string
+	// Chave exigida no header X-Admin-Key dos endpoints /admin; vazio os desativa
+	AdminAPIKey string
+	// Feriados extras no formato "calendário:AAAA-MM-DD", ex.: us:2026-12-24
+	MarketHolidays string
+	// Entrega dos webhooks dos alertas: workers, tentativas e timeout por tentativa
+	AlertWorkers        int
+	AlertMaxAttempts    int
+	AlertWebhookTimeout time.Duration
+}
+
+func Load() Config {
+	if err := godotenv.Load(".env"); err != nil {
+		log.Printf("Arquivo .env não carregado, usando variáveis de ambiente: %v", err)
+	}
+
+	return Config{
+		PriceHistoryMaxPoints: getInt("PRICE_HISTORY_MAX_POINTS", 10000),
+		PriceHistoryMaxAge:    getDuration("PRICE_HISTORY_MAX_AGE", 24*time.Hour),
+		CandleHistoryMax:      getInt("CANDLE_HISTORY_MAX", 1000),
+		TickStoreDir:          os.Getenv("TICKSTORE_DIR"),
+		TickStoreRetention:    getDuration("TICKSTORE_RETENTION", 30*24*time.Hour),
+		YahooURL:              os.Getenv("YAHOO_STREAMER_URL"),
+		YahooReconnect: websocket.ReconnectPolicy{
+			InitialDelay: getDuration("YAHOO_RECONNECT_INITIAL_DELAY", websocket.DefaultReconnectPolicy.InitialDelay),
+			MaxDelay:     getDuration("YAHOO_RECONNECT_MAX_DELAY", websocket.DefaultReconnectPolicy.MaxDelay),
+			Multiplier:   websocket.DefaultReconnectPolicy.Multiplier,
+			Jitter:       websocket.DefaultReconnectPolicy.Jitter,
+			MaxAttempts:  getInt("YAHOO_RECONNECT_MAX_ATTEMPTS", 0),
+		},
+		YahooPingInterval: getDuration("YAHOO_PING_INTERVAL", websocket.DefaultPingInterval),
+		YahooReadTimeout:  getDuration("YAHOO_READ_TIMEOUT", websocket.DefaultReadTimeout),
+		QuoteStaleAfter:   getDuration("QUOTE_STALE_AFTER", currency.DefaultStaleAfter),
+		RecordFile:        os.Getenv("RECORD_FILE"),
+		ReplayFile:        os.Getenv("REPLAY_FILE"),
+		ReplaySpeed:       getFloat("REPLAY_SPEED", 1),
+		ReplayLoop:        os.Getenv("REPLAY_LOOP") == "true",
+		JSONFeed: websocket.JSONFeedConfig{
+			URL:                 os.Getenv("JSONFEED_URL"),
+			SubscribeTemplate:   os.Getenv("JSONFEED_SUBSCRIBE"),
+			UnsubscribeTemplate: os.Getenv("JSONFEED_UNSUBSCRIBE"),
+			ParamTemplate:       os.Getenv("JSONFEED_PARAM"),
+			SymbolField:         os.Getenv("JSONFEED_SYMBOL_FIELD"),
+			PriceField:          os.Getenv("JSONFEED_PRICE_FIELD"),
+			TimeField:           os.Getenv("JSONFEED_TIME_FIELD"),
+			BidField:            os.Getenv("JSONFEED_BID_FIELD"),
+			AskField:            os.Getenv("JSONFEED_ASK_FIELD"),
+			SizeField:           os.Getenv("JSONFEED_SIZE_FIELD"),
+			TimeInSeconds:       os.Getenv("JSONFEED_TIME_IN_SECONDS") == "true",
+		},
+		PairProviders:       getMap("PAIR_PROVIDERS"),
+		PairFallbacks:       getMap("PAIR_FALLBACKS"),
+		FailoverStaleAfter:  getDuration("FAILOVER_STALE_AFTER", time.Minute),
+		CatalogFile:         os.Getenv("CATALOG_FILE"),
+		AdminAPIKey:         os.Getenv("ADMIN_API_KEY"),
+		MarketHolidays:      os.Getenv("MARKET_HOLIDAYS"),
+		AlertWorkers:        getPositiveInt("ALERT_WORKERS", 4),
+		AlertMaxAttempts:    getPositiveInt("ALERT_MAX_ATTEMPTS", 5),
+		AlertWebhookTimeout: getDuration("ALERT_WEBHOOK_TIMEOUT", 10*time.Second),
+	}
+}
+
+// getMap lê uma lista "chave=valor,chave=valor".
+func getMap(key string) map[string]string {
+	result := make(map[string]string)
+	value := os.Getenv(key)
+	if value == "" {
+		return result
+	}
+	for _, item := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok || k == "" {
+			log.Printf("Item inválido em %s: %q", key, item)
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+func getInt(key string, def int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Valor inválido para %s (%q), usando %d", key, value, def)
+		return def
+	}
+	return n
+}
+
+// getPositiveInt é como getInt, mas recusa zero e valores negativos.
+func getPositiveInt(key string, def int) int {
+	n := getInt(key, def)
+	if n <= 0 {
+		log.Printf("Valor inválido para %s (%d), deve ser positivo; usando %d", key, n, def)
+		return def
+	}
+	return n
+}
+
+func getFloat(key string, def float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Valor inválido para %s (%q), usando %v", key, value, def)
+		return def
+	}
+	return f
+}
+
+func getDuration(key string, def time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Valor inválido para %s (%q), usando %s", key, value, def)
+		return def
+	}
+	return d
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"testing"
+	"time"
+	"wsaetherfy/currency"
+	"wsaetherfy/websocket"
+)
+
+func TestLoadDefaults(t *testing.T) {
+	for _, key := range []string{"YAHOO_PING_INTERVAL", "YAHOO_READ_TIMEOUT", "QUOTE_STALE_AFTER"} {
+		t.Setenv(key, "")
+	}
+	cfg := Load()
+	if cfg.YahooPingInterval != websocket.DefaultPingInterval || cfg.YahooReadTimeout != websocket.DefaultReadTimeout {
+		t.Fatalf("heartbeat = %s/%s, want the websocket defaults", cfg.YahooPingInterval, cfg.YahooReadTimeout)
+	}
+	if cfg.QuoteStaleAfter != currency.DefaultStaleAfter {
+		t.Fatalf("QuoteStaleAfter = %s, want %s", cfg.QuoteStaleAfter, currency.DefaultStaleAfter)
+	}
+
+	t.Setenv("QUOTE_STALE_AFTER", "30s")
+	if cfg := Load(); cfg.QuoteStaleAfter != 30*time.Second {
+		t.Fatalf("QuoteStaleAfter = %s, want 30s", cfg.QuoteStaleAfter)
+	}
+}
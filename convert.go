@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"wsaetherfy/currency"
+)
+
+// convertHandler atende GET /convert?from=BRL&to=EUR&amount=1234.56
+func convertHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	caller, ok := authorizeAPICall(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	from := strings.ToUpper(strings.TrimSpace(query.Get("from")))
+	to := strings.ToUpper(strings.TrimSpace(query.Get("to")))
+	if from == "" || to == "" {
+		http.Error(w, "Moedas de origem (from) e destino (to) são obrigatórias", http.StatusBadRequest)
+		return
+	}
+	for _, code := range []string{from, to} {
+		if !currency.IsKnownCurrency(code) {
+			http.Error(w, "Moeda desconhecida: "+code, http.StatusNotFound)
+			return
+		}
+	}
+
+	amount := 1.0
+	if value := query.Get("amount"); value != "" {
+		var err error
+		amount, err = strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+			http.Error(w, "Parâmetro amount inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	response := map[string]interface{}{
+		"from":   from,
+		"to":     to,
+		"amount": amount,
+	}
+	if from == to {
+		response["rate"] = 1.0
+		response["result"] = amount
+		response["legs"] = []legJSON{}
+	} else {
+		rate, err := currency.GetCrossRate(from, to)
+		if err != nil {
+			http.Error(w, "Não foi possível converter: "+err.Error(), http.StatusNotFound)
+			return
+		}
+		response["rate"] = rate.Rate
+		response["result"] = amount * rate.Rate
+		response["timestamp"] = rate.Timestamp
+		response["legs"] = toLegsJSON(rate.Legs)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+	caller.charge(1)
+}
@@ -0,0 +1,32 @@
+package main
+
+import "wsaetherfy/currency"
+
+type legJSON struct {
+	Pair      string  `json:"pair"`
+	Inverted  bool    `json:"inverted"`
+	Price     float64 `json:"price"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+func toLegsJSON(legs []currency.Leg) []legJSON {
+	result := make([]legJSON, 0, len(legs))
+	for _, leg := range legs {
+		result = append(result, legJSON{
+			Pair:      leg.Pair,
+			Inverted:  leg.Inverted,
+			Price:     leg.Price,
+			Timestamp: leg.Timestamp,
+		})
+	}
+	return result
+}
+
+// addCrossRate marca a cotação como sintética e inclui as pernas usadas.
+func addCrossRate(response map[string]interface{}, rate *currency.CrossRate) {
+	if rate == nil {
+		return
+	}
+	response["synthetic"] = true
+	response["legs"] = toLegsJSON(rate.Legs)
+}
@@ -0,0 +1,214 @@
+package currency
+
+import (
+	"sync"
+	"time"
+	"wsaetherfy/yatickerpb"
+)
+
+// Intervalos de candle suportados, na ordem em que são expostos
+var candleIntervals = []struct {
+	Name     string
+	Duration time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"15m", 15 * time.Minute},
+	{"1h", time.Hour},
+	{"1d", 24 * time.Hour},
+}
+
+// Candle agrega os ticks de um par em um intervalo. Start é o início do
+// intervalo em milissegundos, alinhado a UTC.
+type Candle struct {
+	Start  int64
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume int64
+	Ticks  int
+}
+
+// CandleUpdate é o estado atual do candle de um intervalo após um tick.
+type CandleUpdate struct {
+	Interval string
+	Candle   Candle
+}
+
+type candleSeries struct {
+	closed  []Candle
+	current *Candle
+}
+
+// dayVolume é o último volume acumulado informado pelo upstream para um par,
+// com o horário e a fase do mercado do tick.
+type dayVolume struct {
+	volume int64
+	time   int64
+	hours  yatickerpb.Yaticker_MarketHoursType
+}
+
+// CandleAggregator mantém candles OHLCV de cada par em todos os intervalos.
+type CandleAggregator struct {
+	sync.RWMutex
+	series     map[string]map[string]*candleSeries // par -> intervalo -> série
+	dayVolume  map[string]dayVolume
+	maxCandles int
+}
+
+var candles = NewCandleAggregator(1000)
+
+// NewCandleAggregator cria um agregador que guarda no máximo maxCandles
+// candles fechados por par e intervalo.
+func NewCandleAggregator(maxCandles int) *CandleAggregator {
+	return &CandleAggregator{
+		series:     make(map[string]map[string]*candleSeries),
+		dayVolume:  make(map[string]dayVolume),
+		maxCandles: maxCandles,
+	}
+}
+
+// ConfigureCandles define quantos candles fechados são guardados por par e
+// intervalo. Deve ser chamada antes de MonitorAllCurrencies.
+func ConfigureCandles(maxCandles int) {
+	candles = NewCandleAggregator(maxCandles)
+}
+
+// IsCandleInterval informa se o intervalo é suportado.
+func IsCandleInterval(interval string) bool {
+	for _, ci := range candleIntervals {
+		if ci.Name == interval {
+			return true
+		}
+	}
+	return false
+}
+
+// CandleIntervals retorna os nomes dos intervalos suportados.
+func CandleIntervals() []string {
+	names := make([]string, 0, len(candleIntervals))
+	for _, ci := range candleIntervals {
+		names = append(names, ci.Name)
+	}
+	return names
+}
+
+// tickVolume usa o LastSize quando o upstream o informa e, caso contrário, a
+// variação do DayVolume desde o tick anterior do par.
+func (ca *CandleAggregator) tickVolume(pair string, output *yatickerpb.Yaticker) int64 {
+	if output.LastSize > 0 {
+		return output.LastSize
+	}
+	if output.DayVolume <= 0 {
+		return 0
+	}
+	last, found := ca.dayVolume[pair]
+	current := dayVolume{volume: output.DayVolume, time: output.Time, hours: output.MarketHours}
+	ca.dayVolume[pair] = current
+	switch {
+	case !found:
+		return 0
+	case current.volume >= last.volume:
+		return current.volume - last.volume
+	case volumeReset(output.QuoteType, last, current):
+		// O volume do dia foi zerado, então tudo o que foi negociado é novo
+		return current.volume
+	default:
+		// Volume em janela móvel (como o de 24h das criptomoedas) que caiu
+		return 0
+	}
+}
+
+// volumeReset informa se uma queda do volume acumulado corresponde ao início
+// de um novo dia ou sessão. O volume das criptomoedas é de 24h em janela
+// móvel e nunca é zerado.
+func volumeReset(quoteType yatickerpb.Yaticker_QuoteType, last, current dayVolume) bool {
+	if quoteType == yatickerpb.Yaticker_CRYPTOCURRENCY {
+		return false
+	}
+	if current.hours != last.hours {
+		return true
+	}
+	day := (24 * time.Hour).Milliseconds()
+	return current.time/day != last.time/day
+}
+
+// Add incorpora o tick aos candles do par e retorna o estado atualizado de
+// cada intervalo. Ticks anteriores ao candle corrente são ignorados.
+func (ca *CandleAggregator) Add(pair string, output *yatickerpb.Yaticker) []CandleUpdate {
+	ca.Lock()
+	defer ca.Unlock()
+
+	price := float64(output.Price)
+	volume := ca.tickVolume(pair, output)
+
+	byInterval, found := ca.series[pair]
+	if !found {
+		byInterval = make(map[string]*candleSeries)
+		ca.series[pair] = byInterval
+	}
+
+	updates := make([]CandleUpdate, 0, len(candleIntervals))
+	for _, ci := range candleIntervals {
+		series, found := byInterval[ci.Name]
+		if !found {
+			series = &candleSeries{}
+			byInterval[ci.Name] = series
+		}
+
+		durationMs := ci.Duration.Milliseconds()
+		start := output.Time - output.Time%durationMs
+		current := series.current
+		switch {
+		case current != nil && start < current.Start:
+			continue
+		case current == nil || start > current.Start:
+			if current != nil {
+				series.closed = append(series.closed, *current)
+				if len(series.closed) > ca.maxCandles {
+					series.closed = series.closed[len(series.closed)-ca.maxCandles:]
+				}
+			}
+			current = &Candle{Start: start, Open: price, High: price, Low: price}
+			series.current = current
+		}
+
+		if price > current.High {
+			current.High = price
+		}
+		if price < current.Low {
+			current.Low = price
+		}
+		current.Close = price
+		current.Volume += volume
+		current.Ticks++
+
+		updates = append(updates, CandleUpdate{Interval: ci.Name, Candle: *current})
+	}
+	return updates
+}
+
+// Candles retorna até limit candles do par no intervalo, do mais antigo para
+// o mais recente, incluindo o candle ainda em formação.
+func (ca *CandleAggregator) Candles(pair, interval string, limit int) ([]Candle, bool) {
+	ca.RLock()
+	defer ca.RUnlock()
+	series, found := ca.series[pair][interval]
+	if !found {
+		return nil, false
+	}
+	result := make([]Candle, 0, len(series.closed)+1)
+	result = append(result, series.closed...)
+	if series.current != nil {
+		result = append(result, *series.current)
+	}
+	if limit > 0 && len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+	return result, true
+}
+
+func GetCandles(pair, interval string, limit int) ([]Candle, bool) {
+	return candles.Candles(pair, interval, limit)
+}
@@ -0,0 +1,68 @@
+package currency
+
+import (
+	"testing"
+	"wsaetherfy/yatickerpb"
+)
+
+func TestCandleAggregator(t *testing.T) {
+	ca := NewCandleAggregator(10)
+	ticks := []*yatickerpb.Yaticker{
+		{Price: 1.0, Time: 0, DayVolume: 100},
+		{Price: 1.5, Time: 10_000, DayVolume: 150},
+		{Price: 0.5, Time: 20_000, DayVolume: 170},
+		{Price: 1.25, Time: 60_000, LastSize: 7},
+	}
+	for _, tick := range ticks {
+		ca.Add("BTC/USD", tick)
+	}
+
+	got, found := ca.Candles("BTC/USD", "1m", 0)
+	if !found || len(got) != 2 {
+		t.Fatalf("unexpected 1m candles: %+v", got)
+	}
+	want := Candle{Start: 0, Open: 1.0, High: 1.5, Low: 0.5, Close: 0.5, Volume: 70, Ticks: 3}
+	if got[0] != want {
+		t.Fatalf("first candle = %+v, want %+v", got[0], want)
+	}
+	if got[1].Start != 60_000 || got[1].Open != 1.25 || got[1].Volume != 7 {
+		t.Fatalf("unexpected second candle: %+v", got[1])
+	}
+
+	got, _ = ca.Candles("BTC/USD", "5m", 0)
+	if len(got) != 1 || got[0].Ticks != 4 || got[0].Close != 1.25 {
+		t.Fatalf("unexpected 5m candles: %+v", got)
+	}
+}
+
+func TestCandleVolumeResets(t *testing.T) {
+	ca := NewCandleAggregator(10)
+	day := int64(24 * 60 * 60 * 1000)
+
+	// Volume de 24h em janela móvel: quedas não são negociações novas
+	for _, tick := range []*yatickerpb.Yaticker{
+		{Price: 1, Time: 0, DayVolume: 1000, QuoteType: yatickerpb.Yaticker_CRYPTOCURRENCY},
+		{Price: 1, Time: 10_000, DayVolume: 900, QuoteType: yatickerpb.Yaticker_CRYPTOCURRENCY},
+		{Price: 1, Time: 20_000, DayVolume: 950, QuoteType: yatickerpb.Yaticker_CRYPTOCURRENCY},
+		{Price: 1, Time: day + 10_000, DayVolume: 800, QuoteType: yatickerpb.Yaticker_CRYPTOCURRENCY},
+	} {
+		ca.Add("BTC/USD", tick)
+	}
+	got, _ := ca.Candles("BTC/USD", "1d", 0)
+	if len(got) != 2 || got[0].Volume != 50 || got[1].Volume != 0 {
+		t.Fatalf("unexpected rolling volume candles: %+v", got)
+	}
+
+	// Volume do dia zerado na abertura da sessão seguinte
+	for _, tick := range []*yatickerpb.Yaticker{
+		{Price: 1, Time: 0, DayVolume: 1000, MarketHours: yatickerpb.Yaticker_REGULAR_MARKET},
+		{Price: 1, Time: 10_000, DayVolume: 990, MarketHours: yatickerpb.Yaticker_REGULAR_MARKET},
+		{Price: 1, Time: day + 10_000, DayVolume: 30, MarketHours: yatickerpb.Yaticker_PRE_MARKET},
+	} {
+		ca.Add("SPY", tick)
+	}
+	got, _ = ca.Candles("SPY", "1d", 0)
+	if len(got) != 2 || got[0].Volume != 0 || got[1].Volume != 30 {
+		t.Fatalf("unexpected session volume candles: %+v", got)
+	}
+}
@@ -0,0 +1,185 @@
+package currency
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CatalogEntry é um instrumento do catálogo de símbolos e o seu código no
+// Yahoo. Pares de moedas usam Pair no formato BASE/COTADA; ações, índices,
+// ETFs, futuros e opções usam o ticker em Pair e informam o QuoteType em Type
+// (EQUITY, INDEX, ETF, FUTURE, OPTION...). Instrumentos desativados continuam
+// no catálogo, mas não são monitorados.
+type CatalogEntry struct {
+	Pair     string `json:"pair"`
+	Code     string `json:"code"`
+	Type     string `json:"type,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Exchange string `json:"exchange,omitempty"`
+	Currency string `json:"currency,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// catalog guarda os pares monitorados. Começa com currencyMap e pode ser
+// substituído por um arquivo JSON e alterado em tempo de execução.
+var catalog = struct {
+	sync.RWMutex
+	entries map[string]CatalogEntry
+	// Arquivo onde as alterações são salvas; vazio mantém só em memória
+	path string
+	// Feed em execução, avisado das alterações
+	feed *feed
+	// Mantém a ordem das alterações aplicadas ao feed sem segurar o lock do
+	// catálogo durante as assinaturas
+	feedMu sync.Mutex
+}{
+	entries: defaultCatalog(),
+}
+
+func defaultCatalog() map[string]CatalogEntry {
+	entries := make(map[string]CatalogEntry, len(currencyMap))
+	for pair, code := range currencyMap {
+		entries[pair] = CatalogEntry{Pair: pair, Code: code}
+	}
+	return entries
+}
+
+func validateEntry(entry CatalogEntry) error {
+	if entry.Type != "" && !validQuoteType(entry.Type) {
+		return fmt.Errorf("tipo inválido %q para %s", entry.Type, entry.Pair)
+	}
+	if isInstrument(entry.Type) {
+		// Tickers não podem ter "/" para não serem confundidos com pares
+		// nas taxas sintéticas
+		if entry.Pair == "" || strings.ContainsAny(entry.Pair, "/ ") {
+			return fmt.Errorf("ticker inválido %q", entry.Pair)
+		}
+	} else if _, _, ok := SplitPair(entry.Pair); !ok {
+		return fmt.Errorf("par inválido %q, use BASE/COTADA", entry.Pair)
+	}
+	if entry.Code == "" {
+		return fmt.Errorf("código do par %s é obrigatório", entry.Pair)
+	}
+	return nil
+}
+
+// LoadCatalogFile substitui o catálogo pelos pares do arquivo JSON, uma lista
+// de {"pair","code","type","name","exchange","currency","disabled"}, e passa a
+// salvar nele as alterações. Deve ser chamada antes de MonitorAllCurrencies.
+func LoadCatalogFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var list []CatalogEntry
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("erro ao decodificar o catálogo: %v", err)
+	}
+	entries := make(map[string]CatalogEntry, len(list))
+	for _, entry := range list {
+		if err := validateEntry(entry); err != nil {
+			return err
+		}
+		entries[entry.Pair] = entry
+	}
+
+	catalog.Lock()
+	defer catalog.Unlock()
+	catalog.entries = entries
+	catalog.path = path
+	return nil
+}
+
+// saveCatalog grava o catálogo no arquivo configurado. Deve ser chamada com o
+// lock do catálogo.
+func saveCatalog() error {
+	if catalog.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(sortedEntries(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(catalog.path), ".catalog-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), catalog.path)
+}
+
+func sortedEntries() []CatalogEntry {
+	list := make([]CatalogEntry, 0, len(catalog.entries))
+	for _, entry := range catalog.entries {
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Pair < list[j].Pair })
+	return list
+}
+
+// CatalogEntries lista todos os pares do catálogo, inclusive os desativados.
+func CatalogEntries() []CatalogEntry {
+	catalog.RLock()
+	defer catalog.RUnlock()
+	return sortedEntries()
+}
+
+// SetSymbol adiciona, remapeia, ativa ou desativa um par. Com o monitoramento
+// em execução, o par é assinado ou cancelado no provedor sem reiniciar.
+func SetSymbol(entry CatalogEntry) error {
+	if err := validateEntry(entry); err != nil {
+		return err
+	}
+
+	catalog.Lock()
+	old, existed := catalog.entries[entry.Pair]
+	if existed && old == entry {
+		catalog.Unlock()
+		return nil
+	}
+	catalog.entries[entry.Pair] = entry
+	if err := saveCatalog(); err != nil {
+		log.Printf("Erro ao salvar o catálogo de símbolos: %v", err)
+	}
+	f := catalog.feed
+	catalog.feedMu.Lock()
+	catalog.Unlock()
+	defer catalog.feedMu.Unlock()
+
+	// As assinaturas acontecem fora do lock do catálogo, para não bloquear as
+	// leituras enquanto o provedor responde
+	if f != nil {
+		if existed && !old.Disabled {
+			f.removePair(old.Pair, old.Code)
+		}
+		if !entry.Disabled {
+			f.addPair(entry.Pair, entry.Code)
+		}
+	}
+	return nil
+}
+
+// enabledCodes retorna os pares ativos. Deve ser chamada com o lock do catálogo.
+func enabledCodes() map[string]string {
+	codes := make(map[string]string, len(catalog.entries))
+	for pair, entry := range catalog.entries {
+		if !entry.Disabled {
+			codes[pair] = entry.Code
+		}
+	}
+	return codes
+}
@@ -0,0 +1,66 @@
+package currency
+
+import "testing"
+
+func TestSetSymbol(t *testing.T) {
+	saved := catalog.entries
+	catalog.entries = defaultCatalog()
+	defer func() { catalog.entries = saved }()
+
+	if err := SetSymbol(CatalogEntry{Pair: "EURUSD", Code: "EURUSD=X"}); err == nil {
+		t.Fatal("expected error for pair without separator")
+	}
+	if err := SetSymbol(CatalogEntry{Pair: "EUR/BRL"}); err == nil {
+		t.Fatal("expected error for empty code")
+	}
+
+	if err := SetSymbol(CatalogEntry{Pair: "EUR/BRL", Code: "EURBRL=X"}); err != nil {
+		t.Fatal(err)
+	}
+	if code, found := GetCurrencyCode("EUR/BRL"); !found || code != "EURBRL=X" {
+		t.Fatalf("GetCurrencyCode(EUR/BRL) = %q, %v", code, found)
+	}
+
+	if err := SetSymbol(CatalogEntry{Pair: "EUR/BRL", Code: "EURBRL=X", Disabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := GetCurrencyCode("EUR/BRL"); found {
+		t.Fatal("disabled pair still returned")
+	}
+	if _, found := GetAllCurrencyCodes()["EUR/BRL"]; found {
+		t.Fatal("disabled pair still listed")
+	}
+	if len(CatalogEntries()) != len(currencyMap)+1 {
+		t.Fatalf("expected disabled pair to stay in the catalog")
+	}
+}
+
+func TestSetSymbolInstrument(t *testing.T) {
+	saved := catalog.entries
+	catalog.entries = defaultCatalog()
+	defer func() { catalog.entries = saved }()
+
+	for _, entry := range []CatalogEntry{
+		{Pair: "AAPL", Code: "AAPL", Type: "STOCK"},
+		{Pair: "AAPL", Code: "AAPL"},
+		{Pair: "BRK/B", Code: "BRK-B", Type: "EQUITY"},
+	} {
+		if err := SetSymbol(entry); err == nil {
+			t.Fatalf("expected error for %+v", entry)
+		}
+	}
+
+	if err := SetSymbol(CatalogEntry{Pair: "^GSPC", Code: "^GSPC", Type: "INDEX", Exchange: "SNP", Currency: "USD"}); err != nil {
+		t.Fatal(err)
+	}
+	symbol, found := LookupSymbol("^GSPC")
+	if !found {
+		t.Fatal("instrument not found")
+	}
+	if symbol.AssetClass != "index" || symbol.Base != "" || symbol.Currency != "USD" || symbol.Exchange != "SNP" {
+		t.Fatalf("unexpected symbol %+v", symbol)
+	}
+	if IsCrossPair("^GSPC") {
+		t.Fatal("instrument treated as cross pair")
+	}
+}
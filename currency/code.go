@@ -3,9 +3,11 @@ package currency
 import (
 	"log"
 	"sync"
-	"wsaetherfy/websocket"
+	"time"
+	"wsaetherfy/yatickerpb"
 )
 
+// currencyMap é o catálogo padrão, usado quando nenhum arquivo é carregado
 var currencyMap = map[string]string{
 	"EUR/USD": "EURUSD=X",
 	"USD/JPY": "JPY=X",
@@ -30,6 +32,7 @@ var currencyMap = map[string]string{
 	"USD/MYR": "MYR=X",
 	"USD/ZAR": "ZAR=X",
 	"USD/RUB": "RUB=X",
+	"USD/BRL": "BRL=X",
 	"BTC/USD": "BTC-USD",
 	"ETH/USD": "ETH-USD",
 	"USDT/USD": "USDT-USD",
@@ -57,79 +60,271 @@ var currencyMap = map[string]string{
 	"LEO/USD": "LEO-USD",
 }
 
+// GetCurrencyCode retorna o código do par, se ele estiver ativo no catálogo.
 func GetCurrencyCode(pair string) (string, bool) {
-	code, exists := currencyMap[pair]
-	return code, exists
+	catalog.RLock()
+	defer catalog.RUnlock()
+	entry, exists := catalog.entries[pair]
+	if !exists || entry.Disabled {
+		return "", false
+	}
+	return entry.Code, true
 }
 
+// GetAllCurrencyCodes retorna uma cópia dos pares ativos do catálogo.
 func GetAllCurrencyCodes() map[string]string {
-	return currencyMap
+	catalog.RLock()
+	defer catalog.RUnlock()
+	return enabledCodes()
 }
 
+// PriceData guarda um tick do par. Além do preço e do horário, carrega os
+// demais campos do Yaticker para quem pedir a cotação completa.
 type PriceData struct {
-	Price     float64
-	Timestamp int64
+	Price             float64 `json:"price"`
+	Timestamp         int64   `json:"timestamp"`
+	Bid               float64 `json:"bid,omitempty"`
+	Ask               float64 `json:"ask,omitempty"`
+	BidSize           int64   `json:"bid_size,omitempty"`
+	AskSize           int64   `json:"ask_size,omitempty"`
+	LastSize          int64   `json:"last_size,omitempty"`
+	DayHigh           float64 `json:"day_high,omitempty"`
+	DayLow            float64 `json:"day_low,omitempty"`
+	DayVolume         int64   `json:"day_volume,omitempty"`
+	OpenPrice         float64 `json:"open,omitempty"`
+	PreviousClose     float64 `json:"previous_close,omitempty"`
+	Change            float64 `json:"change,omitempty"`
+	ChangePercent     float64 `json:"change_percent,omitempty"`
+	MarketHours       string  `json:"market_hours,omitempty"`
+	QuoteType         string  `json:"quote_type,omitempty"`
+	Exchange          string  `json:"exchange,omitempty"`
+	Currency          string  `json:"currency,omitempty"`
+	ShortName         string  `json:"short_name,omitempty"`
+	PriceHint         int64   `json:"price_hint,omitempty"`
+	Vol24h            int64   `json:"vol_24h,omitempty"`
+	MarketCap         float64 `json:"market_cap,omitempty"`
+	CirculatingSupply float64 `json:"circulating_supply,omitempty"`
+	// Provedor que gerou o tick
+	Source string `json:"source,omitempty"`
+}
+
+// newPriceData converte o tick recebido do upstream.
+func newPriceData(output *yatickerpb.Yaticker) PriceData {
+	return PriceData{
+		Price:             float64(output.Price),
+		Timestamp:         output.Time, // Adicionando o timestamp
+		Bid:               float64(output.Bid),
+		Ask:               float64(output.Ask),
+		BidSize:           output.BidSize,
+		AskSize:           output.AskSize,
+		LastSize:          output.LastSize,
+		DayHigh:           float64(output.DayHigh),
+		DayLow:            float64(output.DayLow),
+		DayVolume:         output.DayVolume,
+		OpenPrice:         float64(output.OpenPrice),
+		PreviousClose:     float64(output.PreviousClose),
+		Change:            float64(output.Change),
+		ChangePercent:     float64(output.ChangePercent),
+		MarketHours:       output.MarketHours.String(),
+		QuoteType:         output.QuoteType.String(),
+		Exchange:          output.Exchange,
+		Currency:          output.Currency,
+		ShortName:         output.ShortName,
+		PriceHint:         output.PriceHint,
+		Vol24h:            output.Vol_24Hr,
+		MarketCap:         output.Marketcap,
+		CirculatingSupply: output.CirculatingSupply,
+	}
 }
 
 type PriceStore struct {
 	sync.RWMutex
-	prices map[string][]PriceData
+	prices    map[string]*priceRing
+	maxPoints int
+	maxAge    time.Duration
+	// Criação do store; as sequências dos ticks só valem dentro dela
+	epoch int64
 }
 
 var (
-	priceStore = &PriceStore{prices: make(map[string][]PriceData)}
+	priceStore = NewPriceStore(10000, 24*time.Hour)
 )
 
-// Função para monitorar todas as moedas
-func MonitorAllCurrencies() {
-	for pair, subs := range GetAllCurrencyCodes() {
-		go func(pair, subs string) {
-			yf := websocket.NewWithSub(subs)
-			if err := yf.Connect(); err != nil {
-				log.Printf("Erro ao conectar para %s: %v", pair, err)
-				return
-			}
-			defer yf.Close()
-
-			if err := yf.Subscribe(); err != nil {
-				log.Printf("Erro ao assinar %s: %v", pair, err)
-				return
-			}
-
-			ticker, err := yf.Ticker()
-			if err != nil {
-				log.Printf("Erro ao obter ticker para %s: %v", pair, err)
-				return
-			}
-
-			for {
-				select {
-				case output := <-ticker:
-					priceStore.Lock()
-					priceData := PriceData{
-						Price:     float64(output.Price),
-						Timestamp: output.Time,  // Adicionando o timestamp
-					}
-					if prices, found := priceStore.prices[pair]; found {
-						priceStore.prices[pair] = append(prices, priceData)
-					} else {
-						priceStore.prices[pair] = []PriceData{priceData}
-					}
-					priceStore.Unlock()
-				}
-			}
-			
-		}(pair, subs)
+// NewPriceStore cria um store que guarda no máximo maxPoints ticks por par,
+// descartando também os mais antigos que maxAge (zero desativa o limite).
+func NewPriceStore(maxPoints int, maxAge time.Duration) *PriceStore {
+	return &PriceStore{
+		prices:    make(map[string]*priceRing),
+		maxPoints: maxPoints,
+		maxAge:    maxAge,
+		epoch:     time.Now().UnixMilli(),
 	}
 }
 
-func GetPrices(pair string) (PriceData, bool) {
-	priceStore.RLock()
-	defer priceStore.RUnlock()
-	prices, found := priceStore.prices[pair]
-	if !found || len(prices) == 0 {
+// ConfigureRetention define os limites do histórico de preços. Deve ser
+// chamada antes de MonitorAllCurrencies.
+func ConfigureRetention(maxPoints int, maxAge time.Duration) {
+	priceStore = NewPriceStore(maxPoints, maxAge)
+}
+
+// Add guarda o tick e retorna a sua sequência no histórico do par.
+func (ps *PriceStore) Add(pair string, data PriceData) uint64 {
+	ps.Lock()
+	defer ps.Unlock()
+	ring, found := ps.prices[pair]
+	if !found {
+		ring = newPriceRing(ps.maxPoints, ps.maxAge.Milliseconds())
+		ps.prices[pair] = ring
+	}
+	return ring.push(data)
+}
+
+func (ps *PriceStore) Last(pair string) (PriceData, bool) {
+	ps.RLock()
+	defer ps.RUnlock()
+	ring, found := ps.prices[pair]
+	if !found {
 		return PriceData{}, false
 	}
-	return prices[len(prices)-1], found  // Retornar o último preço com timestamp
+	return ring.last()
+}
+
+// HistoryPage é uma página de ticks em ordem cronológica. Quando HasMore é
+// verdadeiro, NextCursor deve ser usado para buscar a página seguinte.
+type HistoryPage struct {
+	Ticks      []PriceData
+	NextCursor uint64
+	HasMore    bool
+}
+
+// History retorna até limit ticks do par com from <= Timestamp <= to a partir
+// do cursor informado (zero começa do início).
+func (ps *PriceStore) History(pair string, from, to int64, cursor uint64, limit int) (HistoryPage, bool) {
+	ps.RLock()
+	defer ps.RUnlock()
+	ring, found := ps.prices[pair]
+	if !found {
+		return HistoryPage{}, false
+	}
+	return ring.page(from, to, cursor, limit), true
+}
+
+// Since retorna até limit ticks do par com sequência maior que after, junto
+// com a sequência do primeiro tick retornado.
+func (ps *PriceStore) Since(pair string, after uint64, limit int) ([]PriceData, uint64, bool) {
+	ps.RLock()
+	defer ps.RUnlock()
+	ring, found := ps.prices[pair]
+	if !found {
+		return nil, 0, false
+	}
+	ticks, first := ring.since(after, limit)
+	return ticks, first, true
+}
+
+// SinceTime retorna até limit ticks do par com Timestamp maior que ts, junto
+// com a sequência do primeiro tick retornado. Ao contrário das sequências, os
+// horários continuam válidos depois de um reinício com o histórico recarregado.
+func (ps *PriceStore) SinceTime(pair string, ts int64, limit int) ([]PriceData, uint64, bool) {
+	ps.RLock()
+	defer ps.RUnlock()
+	ring, found := ps.prices[pair]
+	if !found {
+		return nil, 0, false
+	}
+	ticks, first := ring.after(ts, limit)
+	return ticks, first, true
+}
+
+// LastSeq retorna a sequência do último tick do par.
+func (ps *PriceStore) LastSeq(pair string) (uint64, bool) {
+	ps.RLock()
+	defer ps.RUnlock()
+	ring, found := ps.prices[pair]
+	if !found || ring.size == 0 {
+		return 0, false
+	}
+	return ring.nextSeq - 1, true
+}
+
+// Epoch identifica o store. Sequências de outro epoch, como as de antes de um
+// reinício, não correspondem aos ticks deste store.
+func (ps *PriceStore) Epoch() int64 {
+	return ps.epoch
+}
+
+// Range retorna os ticks do par com from <= Timestamp <= to em ordem cronológica.
+func (ps *PriceStore) Range(pair string, from, to int64) []PriceData {
+	ps.RLock()
+	defer ps.RUnlock()
+	ring, found := ps.prices[pair]
+	if !found {
+		return nil
+	}
+	return ring.rangeOf(from, to)
+}
+
+// Função para monitorar todas as moedas através de uma única conexão com o Yahoo
+func MonitorAllCurrencies() {
+	// O feed é criado com o lock do catálogo para não perder alterações feitas
+	// entre a leitura dos pares e o registro do feed
+	catalog.Lock()
+	f := newFeed(enabledCodes())
+	catalog.feed = f
+	catalog.Unlock()
+
+	if err := f.run(storePrice); err != nil {
+		log.Printf("Erro no monitoramento das moedas: %v", err)
+	}
+}
+
+func storePrice(pair, source string, output *yatickerpb.Yaticker) {
+	// Descarta os ticks do provedor alternativo enquanto o principal está ativo
+	if !supervisor.accept(pair, source) {
+		return
+	}
+	markSeen(pair)
+	priceData := newPriceData(output)
+	priceData.Source = source
+	seq := priceStore.Add(pair, priceData)
+	persistPrice(pair, priceData)
+	updates := candles.Add(pair, output)
+
+	// Repassa o tick e os candles atualizados para os clientes conectados em
+	// /ws, com a sessão calculada uma única vez para todos eles
+	priceHub.publish(pair, Update{Pair: pair, Data: priceData, Seq: seq, Session: SessionOf(pair, priceData, nil)})
+	for _, update := range updates {
+		priceHub.PublishCandle(pair, update)
+	}
+	publishCrossRates(pair)
+}
+
+func GetPrices(pair string) (PriceData, bool) {
+	return priceStore.Last(pair) // Retornar o último preço com timestamp
 }
 
+// GetSince retorna os ticks do par posteriores à sequência after. Veja
+// PriceStore.Since.
+func GetSince(pair string, after uint64, limit int) ([]PriceData, uint64, bool) {
+	return priceStore.Since(pair, after, limit)
+}
+
+// GetSinceTime retorna os ticks do par posteriores ao horário ts. Veja
+// PriceStore.SinceTime.
+func GetSinceTime(pair string, ts int64, limit int) ([]PriceData, uint64, bool) {
+	return priceStore.SinceTime(pair, ts, limit)
+}
+
+// GetLastSeq retorna a sequência do último tick do par.
+func GetLastSeq(pair string) (uint64, bool) {
+	return priceStore.LastSeq(pair)
+}
+
+// HistoryEpoch retorna o epoch do histórico de preços atual.
+func HistoryEpoch() int64 {
+	return priceStore.Epoch()
+}
+
+func GetHistory(pair string, from, to int64, cursor uint64, limit int) (HistoryPage, bool) {
+	return priceStore.History(pair, from, to, cursor, limit)
+}
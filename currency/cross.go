@@ -0,0 +1,219 @@
+package currency
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Leg é um par monitorado usado no cálculo de uma taxa sintética. Quando
+// Inverted é verdadeiro, a perna entra no cálculo como 1/Price.
+type Leg struct {
+	Pair      string
+	Inverted  bool
+	Price     float64
+	Timestamp int64
+}
+
+// CrossRate é uma taxa calculada a partir dos pares monitorados. Timestamp é
+// o da perna mais antiga, de modo que a idade da taxa reflete a perna mais
+// desatualizada.
+type CrossRate struct {
+	Pair      string
+	Base      string
+	Quote     string
+	Rate      float64
+	Timestamp int64
+	Legs      []Leg
+}
+
+// PriceData converte a taxa sintética no formato dos ticks.
+func (cr CrossRate) PriceData() PriceData {
+	return PriceData{Price: cr.Rate, Timestamp: cr.Timestamp}
+}
+
+// SplitPair separa um par "BASE/QUOTE" nas duas moedas.
+func SplitPair(pair string) (base, quote string, ok bool) {
+	base, quote, ok = strings.Cut(pair, "/")
+	if !ok || base == "" || quote == "" || strings.Contains(quote, "/") {
+		return "", "", false
+	}
+	return base, quote, true
+}
+
+type crossEdge struct {
+	to       string
+	pair     string
+	inverted bool
+}
+
+// crossGraph liga as moedas através dos pares monitorados, nos dois sentidos.
+func crossGraph(pairs map[string]string) map[string][]crossEdge {
+	graph := make(map[string][]crossEdge)
+	for pair := range pairs {
+		base, quote, ok := SplitPair(pair)
+		if !ok {
+			continue
+		}
+		graph[base] = append(graph[base], crossEdge{to: quote, pair: pair})
+		graph[quote] = append(graph[quote], crossEdge{to: base, pair: pair, inverted: true})
+	}
+	return graph
+}
+
+// resolveCross encontra o caminho com menos pernas de base até quote usando
+// apenas pares com preço no store e calcula a taxa resultante.
+func resolveCross(ps *PriceStore, pairs map[string]string, base, quote string) (CrossRate, error) {
+	result := CrossRate{Pair: base + "/" + quote, Base: base, Quote: quote}
+	if base == quote {
+		return result, fmt.Errorf("as moedas de origem e destino são iguais")
+	}
+
+	graph := crossGraph(pairs)
+	if _, found := graph[base]; !found {
+		return result, fmt.Errorf("moeda desconhecida: %s", base)
+	}
+	if _, found := graph[quote]; !found {
+		return result, fmt.Errorf("moeda desconhecida: %s", quote)
+	}
+
+	type step struct {
+		from string
+		edge crossEdge
+		data PriceData
+	}
+	visited := map[string]step{base: {}}
+	queue := []string{base}
+	for len(queue) > 0 && !hasKey(visited, quote) {
+		current := queue[0]
+		queue = queue[1:]
+		for _, edge := range graph[current] {
+			if hasKey(visited, edge.to) {
+				continue
+			}
+			data, found := ps.Last(edge.pair)
+			if !found || data.Price == 0 {
+				continue
+			}
+			visited[edge.to] = step{from: current, edge: edge, data: data}
+			queue = append(queue, edge.to)
+		}
+	}
+	if !hasKey(visited, quote) {
+		return result, fmt.Errorf("nenhuma cotação disponível para %s", result.Pair)
+	}
+
+	// Reconstrói o caminho de trás para frente
+	var legs []Leg
+	for cur := quote; cur != base; cur = visited[cur].from {
+		s := visited[cur]
+		legs = append([]Leg{{
+			Pair:      s.edge.pair,
+			Inverted:  s.edge.inverted,
+			Price:     s.data.Price,
+			Timestamp: s.data.Timestamp,
+		}}, legs...)
+	}
+
+	result.Rate = 1
+	result.Timestamp = legs[0].Timestamp
+	for _, leg := range legs {
+		if leg.Inverted {
+			result.Rate /= leg.Price
+		} else {
+			result.Rate *= leg.Price
+		}
+		if leg.Timestamp < result.Timestamp {
+			result.Timestamp = leg.Timestamp
+		}
+	}
+	result.Legs = legs
+	return result, nil
+}
+
+func hasKey[V any](m map[string]V, key string) bool {
+	_, found := m[key]
+	return found
+}
+
+// GetCrossRate calcula a taxa entre duas moedas a partir dos pares
+// monitorados, seja direta, invertida ou passando por outras moedas.
+func GetCrossRate(base, quote string) (CrossRate, error) {
+	return resolveCross(priceStore, GetAllCurrencyCodes(), base, quote)
+}
+
+// IsKnownCurrency informa se a moeda faz parte de algum par do catálogo.
+func IsKnownCurrency(code string) bool {
+	return hasKey(crossGraph(GetAllCurrencyCodes()), code)
+}
+
+// IsCrossPair informa se o par não é monitorado diretamente, mas pode ser
+// calculado a partir de moedas conhecidas.
+func IsCrossPair(pair string) bool {
+	if _, exists := GetCurrencyCode(pair); exists {
+		return false
+	}
+	base, quote, ok := SplitPair(pair)
+	if !ok || base == quote {
+		return false
+	}
+	graph := crossGraph(GetAllCurrencyCodes())
+	return hasKey(graph, base) && hasKey(graph, quote)
+}
+
+// GetQuote retorna o último preço do par. Pares não monitorados são
+// calculados como taxas sintéticas, retornadas em cross.
+func GetQuote(pair string) (data PriceData, cross *CrossRate, found bool) {
+	if _, exists := GetCurrencyCode(pair); exists {
+		data, found = GetPrices(pair)
+		return data, nil, found
+	}
+	base, quote, ok := SplitPair(pair)
+	if !ok {
+		return PriceData{}, nil, false
+	}
+	rate, err := GetCrossRate(base, quote)
+	if err != nil {
+		return PriceData{}, nil, false
+	}
+	return rate.PriceData(), &rate, true
+}
+
+// crossWatch guarda os pares sintéticos com assinantes no hub.
+var crossWatch = struct {
+	sync.Mutex
+	pairs map[string]struct{}
+}{pairs: make(map[string]struct{})}
+
+// WatchCrossPair passa a publicar no hub a taxa sintética do par sempre que
+// uma das suas pernas receber um tick.
+func WatchCrossPair(pair string) {
+	crossWatch.Lock()
+	defer crossWatch.Unlock()
+	crossWatch.pairs[pair] = struct{}{}
+}
+
+// publishCrossRates recalcula as taxas sintéticas observadas que dependem do
+// par que acabou de receber um tick. Pares sem assinantes deixam de ser
+// observados.
+func publishCrossRates(pair string) {
+	crossWatch.Lock()
+	defer crossWatch.Unlock()
+	for cross := range crossWatch.pairs {
+		if !priceHub.HasSubscribers(cross) {
+			delete(crossWatch.pairs, cross)
+			continue
+		}
+		base, quote, _ := SplitPair(cross)
+		rate, err := GetCrossRate(base, quote)
+		if err != nil {
+			continue
+		}
+		for _, leg := range rate.Legs {
+			if leg.Pair == pair {
+				priceHub.PublishCross(rate)
+				break
+			}
+		}
+	}
+}
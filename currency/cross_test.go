@@ -0,0 +1,72 @@
+package currency
+
+import (
+	"math"
+	"testing"
+)
+
+func TestResolveCross(t *testing.T) {
+	pairs := map[string]string{
+		"EUR/USD": "EURUSD=X",
+		"GBP/USD": "GBPUSD=X",
+		"USD/JPY": "JPY=X",
+		"USD/BRL": "BRL=X",
+	}
+	ps := NewPriceStore(10, 0)
+	ps.Add("EUR/USD", PriceData{Price: 1.25, Timestamp: 300})
+	ps.Add("GBP/USD", PriceData{Price: 1.5, Timestamp: 200})
+	ps.Add("USD/JPY", PriceData{Price: 150, Timestamp: 100})
+
+	tests := []struct {
+		base, quote string
+		rate        float64
+		legs        int
+		timestamp   int64
+	}{
+		{"EUR", "USD", 1.25, 1, 300},
+		{"USD", "EUR", 0.8, 1, 300},
+		{"JPY", "USD", 1.0 / 150, 1, 100},
+		{"GBP", "EUR", 1.2, 2, 200},
+		{"EUR", "JPY", 187.5, 2, 100},
+	}
+	for _, tt := range tests {
+		got, err := resolveCross(ps, pairs, tt.base, tt.quote)
+		if err != nil {
+			t.Fatalf("%s/%s: %v", tt.base, tt.quote, err)
+		}
+		if math.Abs(got.Rate-tt.rate) > 1e-9 || len(got.Legs) != tt.legs || got.Timestamp != tt.timestamp {
+			t.Errorf("%s/%s = %+v, want rate %v with %d legs at %d", tt.base, tt.quote, got, tt.rate, tt.legs, tt.timestamp)
+		}
+	}
+
+	if _, err := resolveCross(ps, pairs, "BRL", "EUR"); err == nil {
+		t.Error("expected error for pair without live legs")
+	}
+	if _, err := resolveCross(ps, pairs, "XYZ", "EUR"); err == nil {
+		t.Error("expected error for unknown currency")
+	}
+}
+
+func TestResolveCrossDefaultCatalog(t *testing.T) {
+	ps := NewPriceStore(10, 0)
+	ps.Add("USD/BRL", PriceData{Price: 5, Timestamp: 100})
+	ps.Add("EUR/USD", PriceData{Price: 1.25, Timestamp: 200})
+	ps.Add("USD/JPY", PriceData{Price: 150, Timestamp: 300})
+
+	// Exemplo da documentação de /convert?from=BRL&to=EUR
+	got, err := resolveCross(ps, currencyMap, "BRL", "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got.Rate-0.16) > 1e-9 || len(got.Legs) != 2 {
+		t.Fatalf("BRL/EUR = %+v, want 0.16 with 2 legs", got)
+	}
+
+	got, err = resolveCross(ps, currencyMap, "JPY", "BRL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(got.Rate-5.0/150) > 1e-9 || len(got.Legs) != 2 {
+		t.Fatalf("JPY/BRL = %+v, want %v with 2 legs", got, 5.0/150)
+	}
+}
@@ -0,0 +1,86 @@
+package currency
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// failover decide, para cada par com rota alternativa, de qual provedor os
+// ticks são aceitos. Os dois provedores ficam assinados; o alternativo só é
+// usado quando o principal passa staleAfter sem enviar ticks, e o principal
+// volta a ser usado assim que enviar um novo tick.
+type failover struct {
+	sync.Mutex
+	staleAfter time.Duration
+	pairs      map[string]*failoverState
+	now        func() time.Time
+}
+
+type failoverState struct {
+	primary  string
+	fallback string
+	active   string
+	lastSeen map[string]time.Time
+}
+
+var supervisor = newFailover(time.Minute)
+
+func newFailover(staleAfter time.Duration) *failover {
+	return &failover{
+		staleAfter: staleAfter,
+		pairs:      make(map[string]*failoverState),
+		now:        time.Now,
+	}
+}
+
+// ConfigureFailover define após quanto tempo sem ticks o provedor principal
+// de um par é considerado parado.
+func ConfigureFailover(staleAfter time.Duration) {
+	supervisor.Lock()
+	defer supervisor.Unlock()
+	supervisor.staleAfter = staleAfter
+}
+
+// watch registra os provedores principal e alternativo do par.
+func (fo *failover) watch(pair, primary, fallback string) {
+	fo.Lock()
+	defer fo.Unlock()
+	fo.pairs[pair] = &failoverState{
+		primary:  primary,
+		fallback: fallback,
+		active:   primary,
+		lastSeen: map[string]time.Time{primary: fo.now()},
+	}
+}
+
+// accept registra o tick do provedor e informa se ele deve ser usado.
+func (fo *failover) accept(pair, source string) bool {
+	fo.Lock()
+	defer fo.Unlock()
+	state, found := fo.pairs[pair]
+	if !found {
+		return true
+	}
+
+	now := fo.now()
+	state.lastSeen[source] = now
+	if source == state.active {
+		return true
+	}
+
+	switch source {
+	case state.primary:
+		log.Printf("Provedor %s voltou a enviar ticks de %s", source, pair)
+		state.active = source
+		return true
+	case state.fallback:
+		if now.Sub(state.lastSeen[state.primary]) < fo.staleAfter {
+			return false
+		}
+		log.Printf("Provedor %s sem ticks de %s há %s, usando %s", state.primary, pair, now.Sub(state.lastSeen[state.primary]).Round(time.Second), source)
+		state.active = source
+		return true
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package currency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFailover(t *testing.T) {
+	now := time.Unix(0, 0)
+	fo := newFailover(time.Minute)
+	fo.now = func() time.Time { return now }
+	fo.watch("BTC/USD", "yahoo", "jsonfeed")
+
+	if !fo.accept("BTC/USD", "yahoo") {
+		t.Fatal("primary tick rejected")
+	}
+	if fo.accept("BTC/USD", "jsonfeed") {
+		t.Fatal("fallback tick accepted while primary is fresh")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if !fo.accept("BTC/USD", "jsonfeed") {
+		t.Fatal("fallback tick rejected while primary is stale")
+	}
+	if !fo.accept("BTC/USD", "jsonfeed") {
+		t.Fatal("fallback tick rejected after failover")
+	}
+
+	if !fo.accept("BTC/USD", "yahoo") {
+		t.Fatal("primary tick rejected after recovery")
+	}
+	if fo.accept("BTC/USD", "jsonfeed") {
+		t.Fatal("fallback tick accepted after primary recovered")
+	}
+
+	if !fo.accept("EUR/USD", "yahoo") {
+		t.Fatal("tick of pair without fallback rejected")
+	}
+}
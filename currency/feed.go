@@ -0,0 +1,332 @@
+package currency
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"wsaetherfy/websocket"
+	"wsaetherfy/yatickerpb"
+)
+
+// Nome do provedor padrão, usado pelos pares sem rota configurada
+const YahooProvider = "yahoo"
+
+// Route indica de qual provedor, e com qual símbolo, um par é monitorado.
+type Route struct {
+	Provider string
+	Symbol   string
+}
+
+// ParseRoute interpreta uma rota no formato "provedor:símbolo".
+func ParseRoute(spec string) (Route, error) {
+	provider, symbol, ok := strings.Cut(spec, ":")
+	if !ok || provider == "" || symbol == "" {
+		return Route{}, fmt.Errorf("rota inválida %q, use provedor:símbolo", spec)
+	}
+	return Route{Provider: provider, Symbol: symbol}, nil
+}
+
+var (
+	providers = map[string]websocket.Provider{}
+	routes    = map[string]Route{}
+	fallbacks = map[string]Route{}
+)
+
+// RegisterProvider disponibiliza um provedor de cotações para as rotas. Deve
+// ser chamada antes de MonitorAllCurrencies.
+func RegisterProvider(name string, p websocket.Provider) {
+	providers[name] = p
+}
+
+// SetRoute faz o par ser monitorado pelo provedor e símbolo informados em vez
+// do Yahoo. Deve ser chamada antes de MonitorAllCurrencies.
+func SetRoute(pair string, route Route) error {
+	if _, exists := GetCurrencyCode(pair); !exists {
+		return fmt.Errorf("par de moedas não encontrado: %s", pair)
+	}
+	if _, found := providers[route.Provider]; !found && route.Provider != YahooProvider {
+		return fmt.Errorf("provedor não registrado: %s", route.Provider)
+	}
+	routes[pair] = route
+	return nil
+}
+
+// SetFallback define o provedor alternativo usado quando o principal do par
+// para de enviar ticks. Deve ser chamada antes de MonitorAllCurrencies.
+func SetFallback(pair string, route Route) error {
+	code, exists := GetCurrencyCode(pair)
+	if !exists {
+		return fmt.Errorf("par de moedas não encontrado: %s", pair)
+	}
+	if _, found := providers[route.Provider]; !found && route.Provider != YahooProvider {
+		return fmt.Errorf("provedor não registrado: %s", route.Provider)
+	}
+	if route.Provider == routeOf(pair, code).Provider {
+		return fmt.Errorf("o provedor alternativo de %s deve ser diferente do principal", pair)
+	}
+	fallbacks[pair] = route
+	return nil
+}
+
+// routeOf retorna a rota do par, que por padrão é o código Yahoo do catálogo.
+func routeOf(pair, code string) Route {
+	if route, found := routes[pair]; found {
+		return route
+	}
+	return Route{Provider: YahooProvider, Symbol: code}
+}
+
+// feed mantém uma única sessão com cada provedor para todos os pares e
+// distribui as mensagens decodificadas de acordo com o Id do ticker. Pares
+// com provedor alternativo são assinados nos dois provedores.
+type feed struct {
+	mu        sync.Mutex
+	providers map[string]websocket.Provider
+	pairs     map[string]map[string]string // provedor -> símbolo -> par
+	// Provedores que já fizeram a assinatura inicial e aceitam novos símbolos
+	live map[string]bool
+	// Provedores com goroutine em execução; running sinaliza quando algum termina
+	running map[string]bool
+	stopped *sync.Cond
+	handle  func(pair, source string, output *yatickerpb.Yaticker)
+	errs    []error
+}
+
+// Política das tentativas de conexão inicial com cada provedor; as
+// reconexões seguintes ficam a cargo do próprio provedor
+var connectPolicy = websocket.DefaultReconnectPolicy
+
+// subscription é um símbolo a ser assinado ou cancelado em um provedor.
+type subscription struct {
+	provider websocket.Provider
+	symbol   string
+}
+
+func newFeed(codes map[string]string) *feed {
+	f := &feed{
+		providers: make(map[string]websocket.Provider),
+		pairs:     make(map[string]map[string]string),
+		live:      make(map[string]bool),
+		running:   make(map[string]bool),
+	}
+	f.stopped = sync.NewCond(&f.mu)
+	for pair, code := range codes {
+		f.routesOf(pair, code, f.add)
+	}
+	return f
+}
+
+// routesOf chama fn com a rota principal do par e, se houver, a alternativa.
+func (f *feed) routesOf(pair, code string, fn func(pair string, route Route)) {
+	route := routeOf(pair, code)
+	fn(pair, route)
+	if fallback, found := fallbacks[pair]; found {
+		fn(pair, fallback)
+		supervisor.watch(pair, route.Provider, fallback.Provider)
+	}
+}
+
+// add associa o símbolo ao par, criando o provedor se necessário. Com o feed
+// em execução, provedores novos ou que já terminaram (por exemplo, depois de
+// desistir de reconectar) são iniciados. Deve ser chamada com f.mu.
+func (f *feed) add(pair string, route Route) {
+	if f.pairs[route.Provider] == nil {
+		f.pairs[route.Provider] = make(map[string]string)
+	}
+	f.pairs[route.Provider][route.Symbol] = pair
+	p, found := f.providers[route.Provider]
+	if !found {
+		if p, found = providers[route.Provider]; !found {
+			p = websocket.New()
+		}
+		f.providers[route.Provider] = p
+	}
+	if f.handle != nil && !f.running[route.Provider] {
+		f.start(route.Provider, p)
+	}
+}
+
+// lookup retorna o par associado ao símbolo do provedor.
+func (f *feed) lookup(provider, symbol string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	pair, found := f.pairs[provider][symbol]
+	return pair, found
+}
+
+// addPair passa a monitorar o par com o feed em execução.
+func (f *feed) addPair(pair, code string) {
+	var subs []subscription
+	f.mu.Lock()
+	f.routesOf(pair, code, func(pair string, route Route) {
+		f.add(pair, route)
+		if f.live[route.Provider] {
+			subs = append(subs, subscription{f.providers[route.Provider], route.Symbol})
+		}
+	})
+	f.mu.Unlock()
+
+	for _, sub := range subs {
+		if err := sub.provider.Subscribe(sub.symbol); err != nil {
+			log.Printf("Erro ao assinar %s: %v", sub.symbol, err)
+		}
+	}
+}
+
+// removePair deixa de monitorar o par com o feed em execução.
+func (f *feed) removePair(pair, code string) {
+	var subs []subscription
+	f.mu.Lock()
+	route := routeOf(pair, code)
+	candidates := []Route{route}
+	if fallback, found := fallbacks[pair]; found {
+		candidates = append(candidates, fallback)
+	}
+	for _, route := range candidates {
+		if f.pairs[route.Provider][route.Symbol] != pair {
+			continue
+		}
+		delete(f.pairs[route.Provider], route.Symbol)
+		if f.live[route.Provider] {
+			subs = append(subs, subscription{f.providers[route.Provider], route.Symbol})
+		}
+	}
+	f.mu.Unlock()
+
+	for _, sub := range subs {
+		if err := sub.provider.Unsubscribe(sub.symbol); err != nil {
+			log.Printf("Erro ao cancelar a assinatura de %s: %v", sub.symbol, err)
+		}
+	}
+}
+
+// run conecta cada provedor, assina todos os seus símbolos em um único
+// Subscribe e entrega cada tick ao handler do par correspondente, junto com o
+// nome do provedor. Retorna quando todos os provedores forem encerrados.
+func (f *feed) run(handle func(pair, source string, output *yatickerpb.Yaticker)) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handle = handle
+	for name, p := range f.providers {
+		f.start(name, p)
+	}
+	for len(f.running) > 0 {
+		f.stopped.Wait()
+	}
+	return errors.Join(f.errs...)
+}
+
+// start executa o provedor em uma goroutine. Deve ser chamada com f.mu.
+func (f *feed) start(name string, p websocket.Provider) {
+	f.running[name] = true
+	go func() {
+		err := f.runProvider(name, p)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.errs = append(f.errs, fmt.Errorf("%s: %v", name, err))
+		delete(f.running, name)
+		f.stopped.Broadcast()
+	}()
+}
+
+// connect conecta o provedor e faz a assinatura inicial, tentando de novo
+// com espera exponencial até conseguir.
+func (f *feed) connect(name string, p websocket.Provider) (<-chan *yatickerpb.Yaticker, error) {
+	for attempt := 1; ; attempt++ {
+		ticker, err := f.subscribe(name, p)
+		if err == nil {
+			return ticker, nil
+		}
+		if errors.Is(err, websocket.ErrClosed) {
+			return nil, err
+		}
+		if connectPolicy.MaxAttempts > 0 && attempt >= connectPolicy.MaxAttempts {
+			return nil, err
+		}
+		delay := connectPolicy.Delay(attempt)
+		log.Printf("Provedor %s: %v, nova tentativa em %s", name, err, delay.Round(time.Millisecond))
+		time.Sleep(delay)
+	}
+}
+
+// subscribe faz uma tentativa de conexão e assinatura inicial.
+func (f *feed) subscribe(name string, p websocket.Provider) (<-chan *yatickerpb.Yaticker, error) {
+	if err := p.Connect(); err != nil {
+		return nil, fmt.Errorf("erro ao conectar: %w", err)
+	}
+
+	// A assinatura inicial é feita com f.mu para que addPair e removePair
+	// não percam símbolos alterados enquanto ela acontece
+	f.mu.Lock()
+	subs := make([]string, 0, len(f.pairs[name]))
+	for symbol := range f.pairs[name] {
+		subs = append(subs, symbol)
+	}
+	err := p.Subscribe(subs...)
+	f.live[name] = err == nil
+	f.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("erro ao assinar: %v", err)
+	}
+
+	ticker, err := p.Ticks()
+	if err != nil {
+		f.mu.Lock()
+		delete(f.live, name)
+		f.mu.Unlock()
+		return nil, fmt.Errorf("erro ao obter ticker: %v", err)
+	}
+	return ticker, nil
+}
+
+// runProvider entrega os ticks do provedor até o canal ser fechado. O
+// provedor não é fechado ao terminar, para que add possa reiniciá-lo.
+func (f *feed) runProvider(name string, p websocket.Provider) error {
+	if sn, ok := p.(websocket.StateNotifier); ok {
+		done := make(chan struct{})
+		defer close(done)
+		go logStates(name, sn.States(), done)
+	}
+
+	ticker, err := f.connect(name, p)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		f.mu.Lock()
+		delete(f.live, name)
+		f.mu.Unlock()
+	}()
+
+	for output := range ticker {
+		pair, found := f.lookup(name, output.Id)
+		if !found {
+			log.Println("Ticker recebido para símbolo desconhecido:", output.Id)
+			continue
+		}
+		f.handle(pair, name, output)
+	}
+	return fmt.Errorf("ticker encerrado")
+}
+
+// logStates registra as mudanças de estado da conexão com o provedor.
+func logStates(name string, states <-chan websocket.StateEvent, done chan struct{}) {
+	for {
+		select {
+		case event := <-states:
+			switch {
+			case event.Err != nil:
+				log.Printf("Provedor %s: %s (%v)", name, event.State, event.Err)
+			case event.Attempt > 0:
+				log.Printf("Provedor %s: %s (tentativa %d)", name, event.State, event.Attempt)
+			default:
+				log.Printf("Provedor %s: %s", name, event.State)
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package currency
+
+import (
+	"testing"
+	"time"
+	"wsaetherfy/websocket"
+	"wsaetherfy/websocket/yahootest"
+	"wsaetherfy/yatickerpb"
+)
+
+func TestFeedFansOutByID(t *testing.T) {
+	server := yahootest.NewServer(
+		yahootest.Tick("EURUSD=X", 1.25, 1),
+		yahootest.Tick("JPY=X", 150, 2),
+		yahootest.Tick("GBPUSD=X", 1.5, 3),
+	)
+	defer server.Close()
+
+	yf := websocket.New()
+	yf.SetURL(server.URL())
+	RegisterProvider(YahooProvider, yf)
+	defer delete(providers, YahooProvider)
+
+	f := newFeed(map[string]string{"EUR/USD": "EURUSD=X", "USD/JPY": "JPY=X"})
+	received := make(chan string, 10)
+	go f.run(func(pair, source string, output *yatickerpb.Yaticker) {
+		if source != YahooProvider {
+			t.Errorf("unexpected source %q", source)
+		}
+		received <- pair
+	})
+
+	for _, want := range []string{"EUR/USD", "USD/JPY"} {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("got tick for %s, want %s", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+	}
+
+	if subs := server.Subscriptions(); server.Connections() != 1 || len(subs) != 1 || len(subs[0]) != 2 {
+		t.Fatalf("expected a single connection with one subscribe, got %d connections and %v", server.Connections(), subs)
+	}
+}
+
+func TestFeedAddRemovePair(t *testing.T) {
+	server := yahootest.NewServer(
+		yahootest.Tick("EURUSD=X", 1.25, 1),
+		yahootest.Step{Delay: 500 * time.Millisecond, Ticker: &yatickerpb.Yaticker{Id: "EURUSD=X", Price: 1.5, Time: 2}},
+		yahootest.Tick("GBPUSD=X", 1.75, 3),
+	)
+	defer server.Close()
+
+	yf := websocket.New()
+	yf.SetURL(server.URL())
+	RegisterProvider(YahooProvider, yf)
+	defer delete(providers, YahooProvider)
+
+	f := newFeed(map[string]string{"EUR/USD": "EURUSD=X"})
+	received := make(chan string, 10)
+	go f.run(func(pair, source string, output *yatickerpb.Yaticker) {
+		received <- pair
+	})
+
+	next := func() string {
+		select {
+		case pair := <-received:
+			return pair
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for tick")
+			return ""
+		}
+	}
+
+	if pair := next(); pair != "EUR/USD" {
+		t.Fatalf("got tick for %s, want EUR/USD", pair)
+	}
+	f.addPair("GBP/USD", "GBPUSD=X")
+	f.removePair("EUR/USD", "EURUSD=X")
+
+	if pair := next(); pair != "GBP/USD" {
+		t.Fatalf("got tick for %s after remap, want GBP/USD", pair)
+	}
+	if server.Connections() != 1 {
+		t.Fatalf("expected a single connection, got %d", server.Connections())
+	}
+}
+
+func TestFeedRetriesInitialConnect(t *testing.T) {
+	defer func(policy websocket.ReconnectPolicy) { connectPolicy = policy }(connectPolicy)
+	connectPolicy = websocket.ReconnectPolicy{InitialDelay: 10 * time.Millisecond, Multiplier: 1}
+
+	// Nenhum servidor escuta nesse endereço
+	yf := websocket.New()
+	yf.SetURL("ws://127.0.0.1:1")
+	RegisterProvider(YahooProvider, yf)
+	defer delete(providers, YahooProvider)
+
+	f := newFeed(map[string]string{"EUR/USD": "EURUSD=X"})
+	received := make(chan string, 10)
+	go f.run(func(pair, source string, output *yatickerpb.Yaticker) {
+		received <- pair
+	})
+	defer yf.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	server := yahootest.NewServer(yahootest.Tick("EURUSD=X", 1.25, 1))
+	defer server.Close()
+	yf.SetURL(server.URL())
+
+	select {
+	case pair := <-received:
+		if pair != "EUR/USD" {
+			t.Fatalf("got tick for %s, want EUR/USD", pair)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for tick after the server came up")
+	}
+}
@@ -0,0 +1,97 @@
+package currency
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Campos retornados quando o cliente não usa o seletor fields=
+var DefaultFields = []string{"price", "timestamp"}
+
+// Campos disponíveis no seletor fields=, pelo nome exposto na API
+var quoteFields = map[string]func(PriceData) interface{}{
+	"price":              func(pd PriceData) interface{} { return pd.Price },
+	"timestamp":          func(pd PriceData) interface{} { return pd.Timestamp },
+	"bid":                func(pd PriceData) interface{} { return pd.Bid },
+	"ask":                func(pd PriceData) interface{} { return pd.Ask },
+	"spread":             func(pd PriceData) interface{} { return pd.Spread() },
+	"bid_size":           func(pd PriceData) interface{} { return pd.BidSize },
+	"ask_size":           func(pd PriceData) interface{} { return pd.AskSize },
+	"last_size":          func(pd PriceData) interface{} { return pd.LastSize },
+	"day_high":           func(pd PriceData) interface{} { return pd.DayHigh },
+	"day_low":            func(pd PriceData) interface{} { return pd.DayLow },
+	"day_volume":         func(pd PriceData) interface{} { return pd.DayVolume },
+	"open":               func(pd PriceData) interface{} { return pd.OpenPrice },
+	"previous_close":     func(pd PriceData) interface{} { return pd.PreviousClose },
+	"change":             func(pd PriceData) interface{} { return pd.Change },
+	"change_percent":     func(pd PriceData) interface{} { return pd.ChangePercent },
+	"market_hours":       func(pd PriceData) interface{} { return pd.MarketHours },
+	"quote_type":         func(pd PriceData) interface{} { return pd.QuoteType },
+	"exchange":           func(pd PriceData) interface{} { return pd.Exchange },
+	"currency":           func(pd PriceData) interface{} { return pd.Currency },
+	"short_name":         func(pd PriceData) interface{} { return pd.ShortName },
+	"price_hint":         func(pd PriceData) interface{} { return pd.PriceHint },
+	"vol_24h":            func(pd PriceData) interface{} { return pd.Vol24h },
+	"market_cap":         func(pd PriceData) interface{} { return pd.MarketCap },
+	"circulating_supply": func(pd PriceData) interface{} { return pd.CirculatingSupply },
+	"source":             func(pd PriceData) interface{} { return pd.Source },
+}
+
+// Spread retorna a diferença entre ask e bid, ou zero quando o upstream não
+// informou os dois lados.
+func (pd PriceData) Spread() float64 {
+	if pd.Bid == 0 || pd.Ask == 0 {
+		return 0
+	}
+	return pd.Ask - pd.Bid
+}
+
+// AllFields retorna os nomes de todos os campos disponíveis no seletor.
+func AllFields() []string {
+	names := make([]string, 0, len(quoteFields))
+	for name := range quoteFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseFields interpreta o valor do parâmetro fields= ("bid,ask,spread" ou
+// "all"). Um valor vazio retorna DefaultFields.
+func ParseFields(value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "":
+		return DefaultFields, nil
+	case "all":
+		return AllFields(), nil
+	}
+
+	var fields []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, found := quoteFields[name]; !found {
+			return nil, fmt.Errorf("campo desconhecido: %q", name)
+		}
+		fields = append(fields, name)
+	}
+	if len(fields) == 0 {
+		return DefaultFields, nil
+	}
+	return fields, nil
+}
+
+// Fields monta a cotação apenas com os campos selecionados.
+func (pd PriceData) Fields(fields []string) map[string]interface{} {
+	result := make(map[string]interface{}, len(fields))
+	for _, name := range fields {
+		if get, found := quoteFields[name]; found {
+			result[name] = get(pd)
+		}
+	}
+	return result
+}
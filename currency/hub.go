@@ -0,0 +1,199 @@
+package currency
+
+import (
+	"strings"
+	"sync"
+)
+
+// Tamanho padrão do buffer de cada assinante do hub
+const subscriberBufferSize = 256
+
+// Prefixo dos tópicos de candles no hub. Os demais tópicos são pares.
+const candleTopicPrefix = "candles:"
+
+// Update é a mensagem entregue aos assinantes do hub a cada novo tick. Nos
+// tópicos de candles, Candle traz o candle atualizado pelo tick.
+type Update struct {
+	Pair   string
+	Data   PriceData
+	Candle *CandleUpdate
+	// Preenchido quando o par é uma taxa sintética
+	Cross *CrossRate
+	// Sequência do tick no histórico do par (veja PriceStore.Since)
+	Seq uint64
+	// Fase do pregão no momento do tick (veja SessionOf)
+	Session string
+}
+
+// CandleTopic retorna o tópico do hub com os candles do par no intervalo.
+func CandleTopic(interval, pair string) string {
+	return candleTopicPrefix + interval + ":" + pair
+}
+
+// ParseCandleTopic separa o intervalo e o par de um tópico de candles.
+func ParseCandleTopic(topic string) (interval, pair string, ok bool) {
+	if !strings.HasPrefix(topic, candleTopicPrefix) {
+		return "", "", false
+	}
+	interval, pair, ok = strings.Cut(strings.TrimPrefix(topic, candleTopicPrefix), ":")
+	return interval, pair, ok
+}
+
+// Hub distribui os ticks recebidos pelo monitoramento para os clientes
+// registrados em cada tópico (um par ou os candles de um par), sem abrir
+// novas conexões com o upstream.
+type Hub struct {
+	sync.Mutex
+	subscribers map[string]map[*Subscriber]struct{}
+	bufferSize  int
+}
+
+// Subscriber representa um cliente do hub. As mensagens dos tópicos assinados são
+// entregues em C; se o cliente não consumir rápido o suficiente e o buffer
+// encher, ele é removido do hub e C é fechado.
+type Subscriber struct {
+	C       <-chan Update
+	ch      chan Update
+	hub     *Hub
+	topics  map[string]struct{}
+	closed  bool
+	evicted bool
+}
+
+var priceHub = NewHub(subscriberBufferSize)
+
+func NewHub(bufferSize int) *Hub {
+	return &Hub{
+		subscribers: make(map[string]map[*Subscriber]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+// NewSubscriber registra um novo assinante no hub global de preços.
+func NewSubscriber() *Subscriber {
+	return priceHub.NewSubscriber()
+}
+
+func (h *Hub) NewSubscriber() *Subscriber {
+	ch := make(chan Update, h.bufferSize)
+	return &Subscriber{
+		C:      ch,
+		ch:     ch,
+		hub:    h,
+		topics: make(map[string]struct{}),
+	}
+}
+
+// Publish entrega o tick a todos os assinantes do par.
+func (h *Hub) Publish(pair string, data PriceData) {
+	h.publish(pair, Update{Pair: pair, Data: data})
+}
+
+// PublishCandle entrega o candle atualizado aos assinantes dos candles do par
+// no intervalo.
+func (h *Hub) PublishCandle(pair string, candle CandleUpdate) {
+	h.publish(CandleTopic(candle.Interval, pair), Update{Pair: pair, Candle: &candle})
+}
+
+// PublishCross entrega a taxa sintética aos assinantes do par.
+func (h *Hub) PublishCross(rate CrossRate) {
+	data := rate.PriceData()
+	h.publish(rate.Pair, Update{Pair: rate.Pair, Data: data, Cross: &rate, Session: SessionOf(rate.Pair, data, &rate)})
+}
+
+// HasSubscribers informa se o tópico tem algum assinante.
+func (h *Hub) HasSubscribers(topic string) bool {
+	h.Lock()
+	defer h.Unlock()
+	return len(h.subscribers[topic]) > 0
+}
+
+// publish entrega a mensagem aos assinantes do tópico sem bloquear.
+// Assinantes com o buffer cheio são desconectados.
+func (h *Hub) publish(topic string, update Update) {
+	h.Lock()
+	defer h.Unlock()
+	for s := range h.subscribers[topic] {
+		select {
+		case s.ch <- update:
+		default:
+			s.evicted = true
+			h.remove(s)
+		}
+	}
+}
+
+// remove desliga o assinante de todos os pares e fecha o canal. Deve ser
+// chamado com o lock do hub.
+func (h *Hub) remove(s *Subscriber) {
+	if s.closed {
+		return
+	}
+	for topic := range s.topics {
+		delete(h.subscribers[topic], s)
+		if len(h.subscribers[topic]) == 0 {
+			delete(h.subscribers, topic)
+		}
+	}
+	s.topics = nil
+	s.closed = true
+	close(s.ch)
+}
+
+// Subscribe passa a receber as mensagens dos tópicos informados. O tópico de
+// um par é o próprio par; para candles, use CandleTopic.
+func (s *Subscriber) Subscribe(topics ...string) {
+	s.hub.Lock()
+	defer s.hub.Unlock()
+	if s.closed {
+		return
+	}
+	for _, topic := range topics {
+		if s.hub.subscribers[topic] == nil {
+			s.hub.subscribers[topic] = make(map[*Subscriber]struct{})
+		}
+		s.hub.subscribers[topic][s] = struct{}{}
+		s.topics[topic] = struct{}{}
+	}
+}
+
+// Unsubscribe deixa de receber as mensagens dos tópicos informados.
+func (s *Subscriber) Unsubscribe(topics ...string) {
+	s.hub.Lock()
+	defer s.hub.Unlock()
+	if s.closed {
+		return
+	}
+	for _, topic := range topics {
+		delete(s.hub.subscribers[topic], s)
+		if len(s.hub.subscribers[topic]) == 0 {
+			delete(s.hub.subscribers, topic)
+		}
+		delete(s.topics, topic)
+	}
+}
+
+// Topics retorna os tópicos assinados no momento.
+func (s *Subscriber) Topics() []string {
+	s.hub.Lock()
+	defer s.hub.Unlock()
+	topics := make([]string, 0, len(s.topics))
+	for topic := range s.topics {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// Evicted indica se o assinante foi removido por não acompanhar o fluxo.
+func (s *Subscriber) Evicted() bool {
+	s.hub.Lock()
+	defer s.hub.Unlock()
+	return s.evicted
+}
+
+// Close remove o assinante do hub.
+func (s *Subscriber) Close() {
+	s.hub.Lock()
+	defer s.hub.Unlock()
+	s.hub.remove(s)
+}
@@ -0,0 +1,46 @@
+package currency
+
+import "testing"
+
+func TestHubPublish(t *testing.T) {
+	h := NewHub(4)
+	s := h.NewSubscriber()
+	defer s.Close()
+	s.Subscribe("EUR/USD")
+
+	h.Publish("EUR/USD", PriceData{Price: 1.1, Timestamp: 1})
+	h.Publish("BTC/USD", PriceData{Price: 60000, Timestamp: 1})
+
+	update := <-s.C
+	if update.Pair != "EUR/USD" || update.Data.Price != 1.1 {
+		t.Fatalf("unexpected update: %+v", update)
+	}
+	select {
+	case update := <-s.C:
+		t.Fatalf("unexpected update for unsubscribed pair: %+v", update)
+	default:
+	}
+}
+
+func TestHubEvictsSlowConsumer(t *testing.T) {
+	h := NewHub(2)
+	slow := h.NewSubscriber()
+	fast := h.NewSubscriber()
+	defer fast.Close()
+	slow.Subscribe("EUR/USD")
+	fast.Subscribe("EUR/USD")
+
+	for i := 0; i < 3; i++ {
+		h.Publish("EUR/USD", PriceData{Price: 1.1, Timestamp: int64(i)})
+		<-fast.C
+	}
+
+	if !slow.Evicted() {
+		t.Fatal("slow subscriber was not evicted")
+	}
+	for range slow.C {
+	}
+	if fast.Evicted() {
+		t.Fatal("fast subscriber was evicted")
+	}
+}
@@ -0,0 +1,78 @@
+package currency
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Tempo padrão sem ticks após o qual uma cotação é considerada parada
+const DefaultStaleAfter = 2 * time.Minute
+
+// liveness guarda quando cada par recebeu o último tick, pelo relógio local,
+// para sinalizar cotações paradas mesmo quando o upstream repete horários.
+var liveness = struct {
+	sync.RWMutex
+	lastSeen   map[string]time.Time
+	staleAfter time.Duration
+}{
+	lastSeen:   make(map[string]time.Time),
+	staleAfter: DefaultStaleAfter,
+}
+
+// ConfigureStaleness define após quanto tempo sem ticks a cotação de um par é
+// considerada parada. Valores não positivos mantêm o limite atual.
+func ConfigureStaleness(staleAfter time.Duration) {
+	if staleAfter <= 0 {
+		log.Printf("Limite de cotação parada inválido (%s), mantendo o atual", staleAfter)
+		return
+	}
+	liveness.Lock()
+	defer liveness.Unlock()
+	liveness.staleAfter = staleAfter
+}
+
+func markSeen(pair string) {
+	liveness.Lock()
+	defer liveness.Unlock()
+	liveness.lastSeen[pair] = time.Now()
+}
+
+// LastSeen retorna quando o último tick do par foi recebido pelo serviço.
+func LastSeen(pair string) (time.Time, bool) {
+	liveness.RLock()
+	defer liveness.RUnlock()
+	t, found := liveness.lastSeen[pair]
+	return t, found
+}
+
+// Age retorna há quanto tempo o tick foi gerado, pelo seu Timestamp.
+func Age(data PriceData) time.Duration {
+	return time.Since(time.UnixMilli(data.Timestamp))
+}
+
+// IsStale informa se a cotação está parada: o tick é mais antigo que o
+// limite configurado ou o par está sem receber ticks há mais tempo que ele.
+// Para taxas sintéticas, basta uma perna parada.
+func IsStale(pair string, data PriceData, cross *CrossRate) bool {
+	liveness.RLock()
+	staleAfter := liveness.staleAfter
+	liveness.RUnlock()
+	if Age(data) > staleAfter {
+		return true
+	}
+	if cross != nil {
+		for _, leg := range cross.Legs {
+			if !seenWithin(leg.Pair, staleAfter) {
+				return true
+			}
+		}
+		return false
+	}
+	return !seenWithin(pair, staleAfter)
+}
+
+func seenWithin(pair string, d time.Duration) bool {
+	t, found := LastSeen(pair)
+	return found && time.Since(t) <= d
+}
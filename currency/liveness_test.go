@@ -0,0 +1,25 @@
+package currency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsStaleWithDefaultWindow(t *testing.T) {
+	defer ConfigureStaleness(DefaultStaleAfter)
+	// Uma configuração ausente não pode zerar o limite
+	ConfigureStaleness(0)
+
+	markSeen("EUR/USD")
+	now := time.Now()
+	if IsStale("EUR/USD", PriceData{Price: 1.1, Timestamp: now.UnixMilli()}, nil) {
+		t.Fatal("fresh tick reported as stale")
+	}
+	old := now.Add(-DefaultStaleAfter - time.Second)
+	if !IsStale("EUR/USD", PriceData{Price: 1.1, Timestamp: old.UnixMilli()}, nil) {
+		t.Fatal("tick older than the stale window reported as fresh")
+	}
+	if !IsStale("GBP/USD", PriceData{Price: 1.3, Timestamp: now.UnixMilli()}, nil) {
+		t.Fatal("pair never seen reported as fresh")
+	}
+}
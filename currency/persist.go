@@ -0,0 +1,51 @@
+package currency
+
+import (
+	"log"
+	"time"
+)
+
+// Persister guarda os ticks fora da memória para que o histórico sobreviva a
+// reinícios do serviço.
+type Persister interface {
+	// Append grava um tick do par.
+	Append(pair string, data PriceData) error
+	// Load entrega, em ordem cronológica por par, os ticks com Timestamp >= since.
+	Load(since int64, fn func(pair string, data PriceData)) error
+	Close() error
+}
+
+var persister Persister
+
+// EnablePersistence recarrega no PriceStore o histórico guardado pelo
+// persister, dentro da retenção configurada, e passa a gravar nele todos os
+// ticks monitorados. Deve ser chamada depois de ConfigureRetention e antes de
+// MonitorAllCurrencies.
+func EnablePersistence(p Persister) error {
+	var since int64
+	if priceStore.maxAge > 0 {
+		since = time.Now().Add(-priceStore.maxAge).UnixMilli()
+	}
+
+	count := 0
+	err := p.Load(since, func(pair string, data PriceData) {
+		priceStore.Add(pair, data)
+		count++
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Histórico recarregado do disco: %d ticks", count)
+
+	persister = p
+	return nil
+}
+
+func persistPrice(pair string, data PriceData) {
+	if persister == nil {
+		return
+	}
+	if err := persister.Append(pair, data); err != nil {
+		log.Printf("Erro ao gravar tick de %s: %v", pair, err)
+	}
+}
@@ -0,0 +1,165 @@
+package currency
+
+import "sort"
+
+// priceRing guarda o histórico de um par em um buffer circular limitado por
+// quantidade de pontos e, opcionalmente, pela idade dos ticks em milissegundos.
+// O buffer cresce sob demanda até maxPoints, para que pares com poucos ticks
+// não reservem o histórico inteiro.
+type priceRing struct {
+	items     []PriceData
+	start     int // posição do tick mais antigo
+	size      int
+	maxPoints int
+	maxAgeMs  int64
+	nextSeq   uint64 // sequência atribuída ao próximo tick
+}
+
+func newPriceRing(maxPoints int, maxAgeMs int64) *priceRing {
+	if maxPoints < 1 {
+		maxPoints = 1
+	}
+	return &priceRing{
+		maxPoints: maxPoints,
+		maxAgeMs:  maxAgeMs,
+	}
+}
+
+// at retorna o i-ésimo tick em ordem cronológica.
+func (r *priceRing) at(i int) PriceData {
+	return r.items[(r.start+i)%len(r.items)]
+}
+
+// firstSeq retorna a sequência do tick mais antigo ainda guardado.
+func (r *priceRing) firstSeq() uint64 {
+	return r.nextSeq - uint64(r.size)
+}
+
+// push guarda o tick e retorna a sua sequência.
+func (r *priceRing) push(data PriceData) uint64 {
+	seq := r.nextSeq
+	r.nextSeq++
+	if r.size == len(r.items) && len(r.items) < r.maxPoints {
+		// Buffer cheio mas abaixo do limite: reordena a partir do tick mais
+		// antigo e cresce
+		if r.start != 0 {
+			items := make([]PriceData, 0, cap(r.items))
+			items = append(items, r.items[r.start:]...)
+			r.items = append(items, r.items[:r.start]...)
+			r.start = 0
+		}
+		r.items = append(r.items, data)
+		r.size++
+	} else if r.size == len(r.items) {
+		r.items[r.start] = data
+		r.start = (r.start + 1) % len(r.items)
+	} else {
+		r.items[(r.start+r.size)%len(r.items)] = data
+		r.size++
+	}
+
+	// Descarta os ticks mais antigos que a idade máxima
+	if r.maxAgeMs > 0 {
+		for r.size > 1 && data.Timestamp-r.at(0).Timestamp > r.maxAgeMs {
+			r.items[r.start] = PriceData{}
+			r.start = (r.start + 1) % len(r.items)
+			r.size--
+		}
+	}
+	return seq
+}
+
+func (r *priceRing) last() (PriceData, bool) {
+	if r.size == 0 {
+		return PriceData{}, false
+	}
+	return r.at(r.size - 1), true
+}
+
+// search retorna a posição do primeiro tick com Timestamp >= ts.
+func (r *priceRing) search(ts int64) int {
+	return sort.Search(r.size, func(i int) bool {
+		return r.at(i).Timestamp >= ts
+	})
+}
+
+// page retorna até limit ticks com from <= Timestamp <= to, começando pelo
+// tick de sequência cursor (ou pelo mais antigo, se ele já foi descartado).
+func (r *priceRing) page(from, to int64, cursor uint64, limit int) HistoryPage {
+	begin := r.search(from)
+	if cursor > r.firstSeq() {
+		if skip := int(cursor - r.firstSeq()); skip > begin {
+			begin = skip
+		}
+	}
+	end := sort.Search(r.size, func(i int) bool {
+		return r.at(i).Timestamp > to
+	})
+
+	page := HistoryPage{Ticks: []PriceData{}}
+	if begin >= end {
+		return page
+	}
+	if limit > 0 && end-begin > limit {
+		page.HasMore = true
+		page.NextCursor = r.firstSeq() + uint64(begin+limit)
+		end = begin + limit
+	}
+	for i := begin; i < end; i++ {
+		page.Ticks = append(page.Ticks, r.at(i))
+	}
+	return page
+}
+
+// since retorna até limit ticks com sequência maior que after e a sequência
+// do primeiro deles. Ticks já descartados são pulados.
+func (r *priceRing) since(after uint64, limit int) ([]PriceData, uint64) {
+	first := r.firstSeq()
+	begin := 0
+	if after+1 > first {
+		begin = int(after + 1 - first)
+	}
+	if begin >= r.size {
+		return nil, r.nextSeq
+	}
+	end := r.size
+	if limit > 0 && end-begin > limit {
+		end = begin + limit
+	}
+	ticks := make([]PriceData, 0, end-begin)
+	for i := begin; i < end; i++ {
+		ticks = append(ticks, r.at(i))
+	}
+	return ticks, first + uint64(begin)
+}
+
+// after retorna até limit ticks com Timestamp maior que ts e a sequência do
+// primeiro deles.
+func (r *priceRing) after(ts int64, limit int) ([]PriceData, uint64) {
+	begin := r.search(ts + 1)
+	end := r.size
+	if limit > 0 && end-begin > limit {
+		end = begin + limit
+	}
+	ticks := make([]PriceData, 0, end-begin)
+	for i := begin; i < end; i++ {
+		ticks = append(ticks, r.at(i))
+	}
+	return ticks, r.firstSeq() + uint64(begin)
+}
+
+// rangeOf retorna os ticks com from <= Timestamp <= to em ordem cronológica.
+func (r *priceRing) rangeOf(from, to int64) []PriceData {
+	begin := r.search(from)
+	end := sort.Search(r.size, func(i int) bool {
+		return r.at(i).Timestamp > to
+	})
+	if begin >= end {
+		return nil
+	}
+	result := make([]PriceData, 0, end-begin)
+	for i := begin; i < end; i++ {
+		result = append(result, r.at(i))
+	}
+	return result
+}
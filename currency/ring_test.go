@@ -0,0 +1,106 @@
+package currency
+
+import "testing"
+
+func TestPriceRingMaxPoints(t *testing.T) {
+	r := newPriceRing(3, 0)
+	for i := int64(1); i <= 5; i++ {
+		r.push(PriceData{Price: float64(i), Timestamp: i})
+	}
+	got := r.rangeOf(0, 10)
+	if len(got) != 3 || got[0].Timestamp != 3 || got[2].Timestamp != 5 {
+		t.Fatalf("unexpected ring contents: %+v", got)
+	}
+}
+
+func TestPriceRingMaxAge(t *testing.T) {
+	r := newPriceRing(100, 1000)
+	for _, ts := range []int64{0, 500, 1200, 1600} {
+		r.push(PriceData{Timestamp: ts})
+	}
+	got := r.rangeOf(0, 2000)
+	if len(got) != 2 || got[0].Timestamp != 1200 {
+		t.Fatalf("unexpected ring contents: %+v", got)
+	}
+}
+
+func TestPriceRingGrowsOnDemand(t *testing.T) {
+	r := newPriceRing(10000, 1000)
+	for _, ts := range []int64{0, 500, 1200, 1600, 1700, 1800} {
+		r.push(PriceData{Timestamp: ts})
+	}
+	if len(r.items) > 8 {
+		t.Fatalf("ring allocated %d items for 6 ticks", len(r.items))
+	}
+	got := r.rangeOf(0, 2000)
+	if len(got) != 4 || got[0].Timestamp != 1200 || got[3].Timestamp != 1800 {
+		t.Fatalf("unexpected ring contents: %+v", got)
+	}
+	if got := r.rangeOf(50, 10); len(got) != 0 {
+		t.Fatalf("expected empty range when from > to, got %+v", got)
+	}
+}
+
+func TestPriceRingRange(t *testing.T) {
+	r := newPriceRing(10, 0)
+	for ts := int64(10); ts <= 100; ts += 10 {
+		r.push(PriceData{Timestamp: ts})
+	}
+	got := r.rangeOf(25, 50)
+	if len(got) != 3 || got[0].Timestamp != 30 || got[2].Timestamp != 50 {
+		t.Fatalf("unexpected range: %+v", got)
+	}
+	if last, ok := r.last(); !ok || last.Timestamp != 100 {
+		t.Fatalf("unexpected last: %+v", last)
+	}
+}
+
+func TestPriceRingPage(t *testing.T) {
+	r := newPriceRing(4, 0)
+	for ts := int64(1); ts <= 6; ts++ {
+		r.push(PriceData{Timestamp: ts})
+	}
+
+	page := r.page(0, 10, 0, 3)
+	if len(page.Ticks) != 3 || page.Ticks[0].Timestamp != 3 || !page.HasMore {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+	page = r.page(0, 10, page.NextCursor, 3)
+	if len(page.Ticks) != 1 || page.Ticks[0].Timestamp != 6 || page.HasMore {
+		t.Fatalf("unexpected second page: %+v", page)
+	}
+}
+
+func TestPriceRingSince(t *testing.T) {
+	r := newPriceRing(3, 0)
+	for i := int64(0); i < 5; i++ {
+		if seq := r.push(PriceData{Timestamp: i}); seq != uint64(i) {
+			t.Fatalf("push returned seq %d, want %d", seq, i)
+		}
+	}
+
+	// Sequências 0 e 1 já foram descartadas
+	ticks, first := r.since(0, 0)
+	if len(ticks) != 3 || first != 2 || ticks[0].Timestamp != 2 {
+		t.Fatalf("since(0) = %+v from %d", ticks, first)
+	}
+	ticks, first = r.since(3, 0)
+	if len(ticks) != 1 || first != 4 || ticks[0].Timestamp != 4 {
+		t.Fatalf("since(3) = %+v from %d", ticks, first)
+	}
+	if ticks, _ = r.since(4, 0); len(ticks) != 0 {
+		t.Fatalf("since(4) = %+v, want none", ticks)
+	}
+	if ticks, _ = r.since(2, 1); len(ticks) != 1 || ticks[0].Timestamp != 3 {
+		t.Fatalf("since(2, 1) = %+v", ticks)
+	}
+
+	// Retomada por horário, usada depois de um reinício
+	ticks, first = r.after(2, 0)
+	if len(ticks) != 2 || first != 3 || ticks[0].Timestamp != 3 {
+		t.Fatalf("after(2) = %+v from %d", ticks, first)
+	}
+	if ticks, _ = r.after(4, 0); len(ticks) != 0 {
+		t.Fatalf("after(4) = %+v, want none", ticks)
+	}
+}
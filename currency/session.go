@@ -0,0 +1,114 @@
+package currency
+
+import (
+	"time"
+	"wsaetherfy/calendar"
+)
+
+// MarketStatus descreve a sessão atual de um instrumento. Horários em
+// milissegundos.
+type MarketStatus struct {
+	Session  string `json:"session"`
+	Open     bool   `json:"open"`
+	Reason   string `json:"reason,omitempty"`
+	Holiday  string `json:"holiday,omitempty"`
+	Calendar string `json:"calendar,omitempty"`
+	// Fim do último pregão regular, quando o mercado está fechado
+	LastClose int64 `json:"last_close,omitempty"`
+	// Início do próximo pregão regular, quando o mercado está fechado
+	NextOpen int64 `json:"next_open,omitempty"`
+}
+
+// calendarOf escolhe o calendário do instrumento pela classe de ativo e pela
+// bolsa do catálogo ou do último tick.
+func calendarOf(pair string, data PriceData) (*calendar.Calendar, bool) {
+	symbol, found := LookupSymbol(pair)
+	if !found {
+		return nil, false
+	}
+	exchange := symbol.Exchange
+	if exchange == "" {
+		exchange = data.Exchange
+	}
+	return calendar.For(symbol.AssetClass, exchange)
+}
+
+// marketStatusAt calcula a sessão do par no instante now. Sem calendário
+// conhecido, usa o MarketHours do último tick.
+func marketStatusAt(pair string, data PriceData, now time.Time) MarketStatus {
+	cal, found := calendarOf(pair, data)
+	if !found {
+		session := data.MarketHours
+		if session == "" {
+			session = string(calendar.Regular)
+		}
+		return MarketStatus{Session: session, Open: session == string(calendar.Regular)}
+	}
+
+	status := cal.At(now)
+	result := MarketStatus{
+		Session:  string(status.Session),
+		Open:     status.Open,
+		Reason:   status.Reason,
+		Holiday:  status.Holiday,
+		Calendar: cal.Name,
+	}
+	if !status.Open {
+		if closed, ok := cal.LastClose(now); ok {
+			result.LastClose = closed.UnixMilli()
+		}
+		if open, ok := cal.NextOpen(now); ok {
+			result.NextOpen = open.UnixMilli()
+		}
+	}
+	return result
+}
+
+// Market retorna a sessão atual do par. Para taxas sintéticas, o mercado só
+// está aberto se todas as pernas estiverem; vale a primeira perna fechada.
+func Market(pair string, data PriceData, cross *CrossRate) MarketStatus {
+	now := time.Now()
+	if cross == nil {
+		return marketStatusAt(pair, data, now)
+	}
+	var result MarketStatus
+	for i, leg := range cross.Legs {
+		legData, _ := GetPrices(leg.Pair)
+		status := marketStatusAt(leg.Pair, legData, now)
+		if i == 0 || (result.Open && !status.Open) {
+			result = status
+		}
+	}
+	return result
+}
+
+// SessionOf retorna só a fase do pregão do par, sem procurar abertura e
+// fechamento, para ser enviada a cada tick. Taxas sintéticas seguem a mesma
+// regra de Market.
+func SessionOf(pair string, data PriceData, cross *CrossRate) string {
+	now := time.Now()
+	if cross == nil {
+		return sessionAt(pair, data, now)
+	}
+	var result string
+	for i, leg := range cross.Legs {
+		legData, _ := GetPrices(leg.Pair)
+		session := sessionAt(leg.Pair, legData, now)
+		if i == 0 || (result == string(calendar.Regular) && session != string(calendar.Regular)) {
+			result = session
+		}
+	}
+	return result
+}
+
+// sessionAt retorna a fase do pregão do par no instante now.
+func sessionAt(pair string, data PriceData, now time.Time) string {
+	cal, found := calendarOf(pair, data)
+	if !found {
+		if data.MarketHours == "" {
+			return string(calendar.Regular)
+		}
+		return data.MarketHours
+	}
+	return string(cal.At(now).Session)
+}
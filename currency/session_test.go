@@ -0,0 +1,29 @@
+package currency
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarketStatusAt(t *testing.T) {
+	// Sábado, 17 de outubro de 2026
+	saturday := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	fx := marketStatusAt("EUR/USD", PriceData{}, saturday)
+	if fx.Open || fx.Session != "CLOSED" || fx.Reason != "weekend" || fx.Calendar != "fx" {
+		t.Fatalf("unexpected FX status %+v", fx)
+	}
+	if want := time.Date(2026, 10, 16, 21, 0, 0, 0, time.UTC).UnixMilli(); fx.LastClose != want {
+		t.Fatalf("LastClose = %d, want %d", fx.LastClose, want)
+	}
+
+	crypto := marketStatusAt("BTC/USD", PriceData{}, saturday)
+	if !crypto.Open || crypto.LastClose != 0 {
+		t.Fatalf("unexpected crypto status %+v", crypto)
+	}
+
+	unknown := marketStatusAt("XYZ", PriceData{MarketHours: "POST_MARKET"}, saturday)
+	if unknown.Open || unknown.Session != "POST_MARKET" || unknown.Calendar != "" {
+		t.Fatalf("unexpected status without calendar %+v", unknown)
+	}
+}
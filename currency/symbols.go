@@ -0,0 +1,137 @@
+package currency
+
+import (
+	"sort"
+	"strings"
+	"wsaetherfy/yatickerpb"
+)
+
+// Classes de ativo expostas em /symbols. Instrumentos de outros tipos usam o
+// QuoteType em minúsculas (equity, index, etf, future, option...).
+const (
+	AssetFX     = "fx"
+	AssetCrypto = "crypto"
+)
+
+// Symbol descreve um instrumento monitorado e os metadados conhecidos do
+// último tick. Base e Quote só são preenchidos para pares de moedas.
+type Symbol struct {
+	Pair       string `json:"pair"`
+	Code       string `json:"code"`
+	AssetClass string `json:"asset_class"`
+	Base       string `json:"base,omitempty"`
+	Quote      string `json:"quote,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Exchange   string `json:"exchange,omitempty"`
+	Currency   string `json:"currency,omitempty"`
+	Precision  int64  `json:"precision,omitempty"`
+	LastUpdate int64  `json:"last_update,omitempty"`
+}
+
+// isInstrument informa se o tipo do catálogo é um instrumento identificado
+// por ticker em vez de um par BASE/COTADA.
+func isInstrument(quoteType string) bool {
+	switch quoteType {
+	case "", "CURRENCY", "CRYPTOCURRENCY":
+		return false
+	}
+	return true
+}
+
+// validQuoteType informa se o tipo existe no Yaticker e pode ser monitorado.
+func validQuoteType(quoteType string) bool {
+	switch quoteType {
+	case "NONE", "HEARTBEAT", "ALTSYMBOL":
+		return false
+	}
+	_, found := yatickerpb.Yaticker_QuoteType_value[quoteType]
+	return found
+}
+
+// assetClass usa o QuoteType do último tick, depois o tipo do catálogo e,
+// sem nenhum dos dois, o formato do código do Yahoo ("EURUSD=X" para câmbio,
+// "BTC-USD" para cripto).
+func assetClass(code string, quoteTypes ...string) string {
+	for _, quoteType := range quoteTypes {
+		switch quoteType {
+		case "CURRENCY":
+			return AssetFX
+		case "CRYPTOCURRENCY":
+			return AssetCrypto
+		case "", "NONE":
+		default:
+			return strings.ToLower(quoteType)
+		}
+	}
+	if strings.HasSuffix(code, "=X") {
+		return AssetFX
+	}
+	return AssetCrypto
+}
+
+func symbolOf(entry CatalogEntry) Symbol {
+	symbol := Symbol{
+		Pair:     entry.Pair,
+		Code:     entry.Code,
+		Name:     entry.Name,
+		Exchange: entry.Exchange,
+		Currency: entry.Currency,
+	}
+	if !isInstrument(entry.Type) {
+		symbol.Base, symbol.Quote, _ = SplitPair(entry.Pair)
+	}
+	last, found := priceStore.Last(entry.Pair)
+	if found {
+		symbol.Precision = last.PriceHint
+		symbol.LastUpdate = last.Timestamp
+		if symbol.Name == "" {
+			symbol.Name = last.ShortName
+		}
+		if symbol.Exchange == "" {
+			symbol.Exchange = last.Exchange
+		}
+		if symbol.Currency == "" {
+			symbol.Currency = last.Currency
+		}
+	}
+	if symbol.Currency == "" {
+		symbol.Currency = symbol.Quote
+	}
+	symbol.AssetClass = assetClass(entry.Code, last.QuoteType, entry.Type)
+	return symbol
+}
+
+// LookupSymbol retorna os metadados do instrumento, se ele estiver ativo.
+func LookupSymbol(pair string) (Symbol, bool) {
+	catalog.RLock()
+	entry, exists := catalog.entries[pair]
+	catalog.RUnlock()
+	if !exists || entry.Disabled {
+		return Symbol{}, false
+	}
+	return symbolOf(entry), true
+}
+
+// Symbols lista os instrumentos monitorados em ordem alfabética. Com class
+// preenchido, retorna só os dessa classe.
+func Symbols(class string) []Symbol {
+	catalog.RLock()
+	entries := make([]CatalogEntry, 0, len(catalog.entries))
+	for _, entry := range catalog.entries {
+		if !entry.Disabled {
+			entries = append(entries, entry)
+		}
+	}
+	catalog.RUnlock()
+
+	symbols := make([]Symbol, 0, len(entries))
+	for _, entry := range entries {
+		symbol := symbolOf(entry)
+		if class != "" && symbol.AssetClass != class {
+			continue
+		}
+		symbols = append(symbols, symbol)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].Pair < symbols[j].Pair })
+	return symbols
+}
@@ -0,0 +1,27 @@
+package currency
+
+import "testing"
+
+func TestAssetClass(t *testing.T) {
+	tests := []struct {
+		code, quoteType, want string
+	}{
+		{"EURUSD=X", "", AssetFX},
+		{"BTC-USD", "", AssetCrypto},
+		{"BTC-USD", "CRYPTOCURRENCY", AssetCrypto},
+		{"JPY=X", "CURRENCY", AssetFX},
+		{"EURUSD=X", "NONE", AssetFX},
+		{"AAPL", "EQUITY", "equity"},
+		{"ES=F", "FUTURE", "future"},
+	}
+	for _, tt := range tests {
+		if got := assetClass(tt.code, tt.quoteType); got != tt.want {
+			t.Errorf("assetClass(%q, %q) = %q, want %q", tt.code, tt.quoteType, got, tt.want)
+		}
+	}
+
+	// Sem ticks, o tipo do catálogo decide
+	if got := assetClass("SPY", "", "ETF"); got != "etf" {
+		t.Errorf("assetClass with catalog type = %q, want etf", got)
+	}
+}
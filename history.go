@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+	"wsaetherfy/currency"
+)
+
+const (
+	defaultHistoryLimit = 500
+	maxHistoryLimit     = 5000
+)
+
+type historyResponse struct {
+	Pair       string                   `json:"pair"`
+	Ticks      []map[string]interface{} `json:"ticks"`
+	NextCursor string                   `json:"next_cursor,omitempty"`
+}
+
+// parseTimeParam aceita timestamps em milissegundos (como o campo timestamp
+// das respostas) ou datas no formato RFC 3339.
+func parseTimeParam(value string, def int64) (int64, error) {
+	if value == "" {
+		return def, nil
+	}
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ms, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, fmt.Errorf("data inválida: %q", value)
+	}
+	return t.UnixMilli(), nil
+}
+
+// historyHandler atende GET /prices/history?pair=&from=&to=&limit=&cursor=&fields=
+func historyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	caller, ok := authorizeAPICall(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	pair := query.Get("pair")
+	if pair == "" {
+		http.Error(w, "Par de moedas é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	from, err := parseTimeParam(query.Get("from"), 0)
+	if err != nil {
+		http.Error(w, "Parâmetro from inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := parseTimeParam(query.Get("to"), math.MaxInt64)
+	if err != nil {
+		http.Error(w, "Parâmetro to inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultHistoryLimit
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			http.Error(w, "Parâmetro limit inválido", http.StatusBadRequest)
+			return
+		}
+		if limit > maxHistoryLimit {
+			limit = maxHistoryLimit
+		}
+	}
+
+	fields, err := currency.ParseFields(query.Get("fields"))
+	if err != nil {
+		http.Error(w, "Parâmetro fields inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var cursor uint64
+	if value := query.Get("cursor"); value != "" {
+		cursor, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			http.Error(w, "Parâmetro cursor inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, found := currency.GetHistory(pair, from, to, cursor, limit)
+	if !found {
+		http.Error(w, "Par de moedas não encontrado", http.StatusNotFound)
+		return
+	}
+
+	response := historyResponse{Pair: pair, Ticks: make([]map[string]interface{}, 0, len(page.Ticks))}
+	for _, tick := range page.Ticks {
+		response.Ticks = append(response.Ticks, tick.Fields(fields))
+	}
+	if page.HasMore {
+		response.NextCursor = strconv.FormatUint(page.NextCursor, 10)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+	caller.charge(1)
+}
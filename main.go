@@ -1,17 +1,26 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
+	"wsaetherfy/alerts"
+	"wsaetherfy/calendar"
+	"wsaetherfy/config"
 	"wsaetherfy/currency"
 	"wsaetherfy/supabase"
+	"wsaetherfy/tickstore"
+	wsy "wsaetherfy/websocket"
 
 	"github.com/gorilla/websocket"
 	supa "github.com/supabase-community/supabase-go"
-	wsy "wsaetherfy/websocket"
 	"wsaetherfy/cronjob"
 )
 
@@ -34,20 +43,14 @@ func initializeAndRefreshSupabaseConnection() {
 }
 
 func wsHandler(w http.ResponseWriter, r *http.Request) {
-	apiKey := r.Header.Get("X-API-Key")
-	if apiKey == "" {
-		http.Error(w, "API key is required", http.StatusUnauthorized)
+	if _, ok := verifyAPIKey(w, r); !ok {
 		return
 	}
 
-	valid, err := supabase.VerifyAPIKey(supabaseClient, apiKey)
+	// Campos enviados em cada tick, escolhidos na conexão com /ws?fields=
+	fields, err := currency.ParseFields(r.URL.Query().Get("fields"))
 	if err != nil {
-		log.Println("Erro ao verificar a chave API:", err)
-		http.Error(w, "Erro interno do servidor", http.StatusInternalServerError)
-		return
-	}
-	if !valid {
-		http.Error(w, "Chave API inválida", http.StatusUnauthorized)
+		http.Error(w, "Parâmetro fields inválido: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -58,144 +61,195 @@ func wsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Close()
 
-	_, message, err := conn.ReadMessage()
-	if err != nil {
-		log.Println("Erro ao ler mensagem do WebSocket:", err)
-		return
-	}
-
-	pair := string(message)
-	_, exists := currency.GetAllCurrencyCodes()[pair]
-	if !exists {
-		log.Println("Par de moedas não encontrado:", pair)
-		conn.WriteMessage(websocket.TextMessage, []byte("Par de moedas inválido"))
-		return
-	}
-
-	yf := wsy.NewWithSub(currency.GetAllCurrencyCodes()[pair])
-	if err := yf.Connect(); err != nil {
-		log.Println("Erro ao conectar:", err)
-		return
-	}
-	defer yf.Close()
-
-	if err := yf.Subscribe(); err != nil {
-		log.Println("Erro ao assinar:", err)
-		return
-	}
-
-	ticker, err := yf.Ticker()
-	if err != nil {
-		log.Println("Erro ao obter ticker:", err)
-		return
-	}
+	conn.SetReadLimit(wsMaxMessageSize)
+
+	sub := currency.NewSubscriber()
+	defer sub.Close()
+
+	// Lê as mensagens de controle durante toda a vida da conexão. As escritas
+	// ficam todas no loop principal, pois a conexão aceita um único escritor.
+	commands := make(chan []byte)
+	closed := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(closed)
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			select {
+			case commands <- message:
+			case <-done:
+				return
+			}
+		}
+	}()
 
 	for {
 		select {
-		case output := <-ticker:
-			message := map[string]interface{}{
-				"pair":      pair,
-				"price":     output.Price,
-				"timestamp": output.Time,
+		case message := <-commands:
+			cmd, err := parseWsCommand(message)
+			var response interface{}
+			if err != nil {
+				response = wsError{Type: "error", Error: err.Error()}
+			} else {
+				response = handleWsCommand(sub, cmd)
+			}
+			if err := conn.WriteJSON(response); err != nil {
+				log.Println("Erro ao enviar mensagem via WebSocket:", err)
+				return
+			}
+		case update, ok := <-sub.C:
+			if !ok {
+				log.Println("Cliente WebSocket lento desconectado")
+				conn.WriteJSON(wsError{Type: "error", Error: "Conexão encerrada: cliente não acompanhou o fluxo de preços"})
+				return
+			}
+			var message interface{}
+			if update.Candle != nil {
+				message = wsCandleMessage(update.Pair, *update.Candle)
+			} else {
+				price := update.Data.Fields(fields)
+				price["type"] = "price"
+				price["pair"] = update.Pair
+				price["session"] = update.Session
+				addCrossRate(price, update.Cross)
+				message = price
 			}
 			err := conn.WriteJSON(message)
 			if err != nil {
 				log.Println("Erro ao enviar mensagem via WebSocket:", err)
 				return
 			}
+		case <-closed:
+			return
 		}
 	}
 }
 
-func priceHandler(w http.ResponseWriter, r *http.Request) {
-	apiKey := r.Header.Get("X-API-Key")
-	if apiKey == "" {
-		http.Error(w, "API key is required", http.StatusUnauthorized)
-		return
-	}
+// Tempo máximo de espera pelas requisições em andamento ao desligar
+const shutdownTimeout = 10 * time.Second
 
-	valid, err := supabase.VerifyAPIKey(supabaseClient, apiKey)
-	if err != nil {
-		log.Println("Erro ao verificar a chave API:", err)
-		http.Error(w, "Erro interno do servidor", http.StatusInternalServerError)
-		return
-	}
-	if !valid {
-		http.Error(w, "Chave API inválida", http.StatusUnauthorized)
-		return
-	}
-
-	userId, err := supabase.GetUserIdByApiKey(supabaseClient, apiKey)
-	if err != nil {
-		log.Printf("Error getting user ID by API key: %v", err)
-		http.Error(w, "Error getting user ID by API key", http.StatusInternalServerError)
-		return
-	}
-
-	subscriptionStatus, err := supabase.GetSubscriptionStatus(supabaseClient, userId)
-	if err != nil {
-		log.Printf("Error getting subscription status: %v", err)
-		http.Error(w, "Error getting subscription status", http.StatusInternalServerError)
-		return
+func main() {
+	// SIGINT e SIGTERM encerram o servidor e rodam os defers, como o
+	// fechamento do armazenamento de ticks
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg := config.Load()
+	adminAPIKey = cfg.AdminAPIKey
+	if cfg.CatalogFile != "" {
+		if err := currency.LoadCatalogFile(cfg.CatalogFile); err != nil {
+			log.Fatalf("Erro ao carregar o catálogo de símbolos: %v", err)
+		}
 	}
+	currency.ConfigureRetention(cfg.PriceHistoryMaxPoints, cfg.PriceHistoryMaxAge)
+	currency.ConfigureCandles(cfg.CandleHistoryMax)
 
-	if subscriptionStatus != "active" {
-		http.Error(w, "Subscription is not active", http.StatusUnauthorized)
-		return
+	// Recarrega o histórico persistido e passa a gravar os novos ticks
+	if cfg.TickStoreDir != "" {
+		store, err := tickstore.Open(cfg.TickStoreDir, cfg.TickStoreRetention)
+		if err != nil {
+			log.Fatalf("Erro ao abrir o armazenamento de ticks: %v", err)
+		}
+		defer store.Close()
+		if err := currency.EnablePersistence(store); err != nil {
+			log.Fatalf("Erro ao recarregar o histórico de preços: %v", err)
+		}
 	}
 
-	maxApiCalls, apiCalls, err := supabase.GetApiUsageByUserId(supabaseClient, userId)
-	if err != nil {
-		log.Printf("Error getting API usage by user ID: %v", err)
-		http.Error(w, "Error getting API usage by user ID", http.StatusInternalServerError)
-		return
-	}
+	go cronjob.StartCronJob()
+	// Inicializar e reiniciar a conexão Supabase a cada 1 hora em uma goroutine separada
+	go initializeAndRefreshSupabaseConnection()
 
-	if apiCalls >= maxApiCalls {
-		http.Error(w, "API usage limit exceeded", http.StatusTooManyRequests)
-		return
+	// Provedores alternativos e rotas por par
+	if cfg.ReplayFile != "" {
+		// Reproduz uma gravação no lugar do streamer do Yahoo
+		currency.RegisterProvider(currency.YahooProvider, wsy.NewReplay(cfg.ReplayFile, cfg.ReplaySpeed, cfg.ReplayLoop))
+	} else {
+		yf := wsy.New()
+		yf.SetURL(cfg.YahooURL)
+		yf.SetReconnectPolicy(cfg.YahooReconnect)
+		yf.SetHeartbeat(cfg.YahooPingInterval, cfg.YahooReadTimeout)
+		if cfg.RecordFile != "" {
+			rec, err := wsy.NewRecorder(cfg.RecordFile)
+			if err != nil {
+				log.Fatalf("Erro ao abrir o arquivo de gravação: %v", err)
+			}
+			defer rec.Close()
+			yf.SetRecorder(rec)
+		}
+		currency.RegisterProvider(currency.YahooProvider, yf)
 	}
-
-	pair := r.URL.Query().Get("pair")
-	if pair == "" {
-		http.Error(w, "Par de moedas é obrigatório", http.StatusBadRequest)
-		return
+	if cfg.JSONFeed.URL != "" {
+		currency.RegisterProvider("jsonfeed", wsy.NewJSONFeed(cfg.JSONFeed))
 	}
-
-	priceData, found := currency.GetPrices(pair)
-	if !found {
-		http.Error(w, "Par de moedas não encontrado", http.StatusNotFound)
-		return
+	for pair, spec := range cfg.PairProviders {
+		route, err := currency.ParseRoute(spec)
+		if err == nil {
+			err = currency.SetRoute(pair, route)
+		}
+		if err != nil {
+			log.Fatalf("Erro ao configurar o provedor de %s: %v", pair, err)
+		}
 	}
-
-	response := map[string]interface{}{
-		"pair":      pair,
-		"price":     priceData.Price,
-		"timestamp": priceData.Timestamp,
+	for pair, spec := range cfg.PairFallbacks {
+		route, err := currency.ParseRoute(spec)
+		if err == nil {
+			err = currency.SetFallback(pair, route)
+		}
+		if err != nil {
+			log.Fatalf("Erro ao configurar o provedor alternativo de %s: %v", pair, err)
+		}
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-	err = supabase.UpdateApiUserUsage(supabaseClient, userId, apiCalls+1)
-	if err != nil {
-		log.Printf("Error updating API user usage: %v", err)
+	currency.ConfigureFailover(cfg.FailoverStaleAfter)
+	currency.ConfigureStaleness(cfg.QuoteStaleAfter)
+	if err := calendar.AddHolidays(cfg.MarketHolidays); err != nil {
+		log.Fatalf("Erro ao configurar os feriados: %v", err)
 	}
-}
-
-func main() {
-	go cronjob.StartCronJob()
-	// Inicializar e reiniciar a conexão Supabase a cada 1 hora em uma goroutine separada
-	go initializeAndRefreshSupabaseConnection()
 
 	// Inicializar monitoramento de todas as moedas
 	go currency.MonitorAllCurrencies()
 
+	// Avalia os alertas de preço a cada tick e entrega os webhooks
+	dispatcher := alerts.NewDispatcher(supabaseAlertStore{}, cfg.AlertWorkers, cfg.AlertMaxAttempts, cfg.AlertWebhookTimeout)
+	alertEvaluator = alerts.NewEvaluator(supabaseAlertStore{}, dispatcher)
+	go alertEvaluator.Run(ctx)
+
 	// Configurar handlers HTTP
 	http.HandleFunc("/ws", wsHandler)
 	http.HandleFunc("/prices", priceHandler)
-
-	// Inicializar servidor
+	http.HandleFunc("/prices/history", historyHandler)
+	http.HandleFunc("/prices/batch", batchPriceHandler)
+	http.HandleFunc("/candles", candlesHandler)
+	http.HandleFunc("/convert", convertHandler)
+	http.HandleFunc("/symbols", symbolsHandler)
+	http.HandleFunc("/admin/symbols", adminSymbolsHandler)
+	http.HandleFunc("/alerts", alertsHandler)
+	http.HandleFunc("/alerts/deliveries", alertDeliveriesHandler)
+	http.HandleFunc("/stream", streamHandler)
+
+	// Inicializar servidor. As requisições herdam ctx para que os streams
+	// abertos terminem no desligamento
 	port := ":8081"
-	fmt.Println("Servidor WebSocket e HTTP rodando na porta", port)
-	log.Fatal(http.ListenAndServe(port, nil))
+	server := &http.Server{
+		Addr:        port,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		fmt.Println("Servidor WebSocket e HTTP rodando na porta", port)
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("Desligando o servidor...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Erro ao desligar o servidor: %v", err)
+	}
 }
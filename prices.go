@@ -0,0 +1,258 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"wsaetherfy/calendar"
+	"wsaetherfy/currency"
+)
+
+const (
+	// Quantidade máxima de pares por requisição em lote
+	maxBatchPairs = 100
+	// Cada chamada contabilizada cobre até este número de cotações do lote
+	batchPairsPerCall = 10
+)
+
+// quoteParams reúne as opções comuns às consultas de cotação.
+type quoteParams struct {
+	fields []string
+	maxAge time.Duration
+}
+
+// quoteError é o erro de uma cotação, com o status HTTP correspondente.
+type quoteError struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+func parseQuoteParams(fieldsValue, maxAgeValue string) (quoteParams, error) {
+	var params quoteParams
+	var err error
+	params.fields, err = currency.ParseFields(fieldsValue)
+	if err != nil {
+		return params, fmt.Errorf("Parâmetro fields inválido: %v", err)
+	}
+	if maxAgeValue != "" {
+		params.maxAge, err = parseMaxAge(maxAgeValue)
+		if err != nil {
+			return params, fmt.Errorf("Parâmetro max_age inválido: %v", err)
+		}
+	}
+	return params, nil
+}
+
+// parseMaxAge aceita durações ("30s", "5m") ou milissegundos.
+func parseMaxAge(value string) (time.Duration, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil && ms > 0 {
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("use uma duração como 30s ou um valor em milissegundos")
+	}
+	return d, nil
+}
+
+// buildQuote monta a cotação do par com os metadados de idade e origem.
+func buildQuote(pair string, params quoteParams) (map[string]interface{}, *quoteError) {
+	priceData, cross, found := currency.GetQuote(pair)
+	if !found {
+		return nil, &quoteError{Status: http.StatusNotFound, Error: "Par de moedas não encontrado"}
+	}
+
+	age := currency.Age(priceData)
+	market := currency.Market(pair, priceData, cross)
+	if params.maxAge > 0 && age > params.maxAge {
+		message := fmt.Sprintf("Cotação de %s desatualizada: último tick há %s, acima do max_age de %s", pair, age.Round(time.Second), params.maxAge)
+		if closed := marketMessage(market); closed != "" {
+			message += ". " + closed
+		}
+		return nil, &quoteError{Status: http.StatusServiceUnavailable, Error: message}
+	}
+
+	response := priceData.Fields(params.fields)
+	response["pair"] = pair
+	if symbol, found := currency.LookupSymbol(pair); found {
+		response["asset_class"] = symbol.AssetClass
+	}
+	addCrossRate(response, cross)
+	response["age_ms"] = age.Milliseconds()
+	response["source"] = priceData.Source
+	if cross != nil {
+		response["source"] = "synthetic"
+	}
+	// Sem pregão, a falta de ticks é esperada e a cotação não é parada
+	response["stale"] = market.Session != string(calendar.Closed) && currency.IsStale(pair, priceData, cross)
+	response["market"] = market
+	if closed := marketMessage(market); closed != "" {
+		response["message"] = closed
+	}
+	if lastSeen, found := currency.LastSeen(pair); found {
+		response["last_seen"] = lastSeen.UnixMilli()
+	}
+	return response, nil
+}
+
+// marketMessage explica por que o mercado está fechado e quando foi o último
+// fechamento. Retorna vazio se houver pregão.
+func marketMessage(market currency.MarketStatus) string {
+	if market.Session != string(calendar.Closed) {
+		return ""
+	}
+	message := "Mercado fechado"
+	switch market.Reason {
+	case calendar.ReasonWeekend:
+		message += " (fim de semana)"
+	case calendar.ReasonHoliday:
+		message += " (feriado: " + market.Holiday + ")"
+	case calendar.ReasonAfterHours:
+		message += " (fora do horário de negociação)"
+	}
+	if market.LastClose > 0 {
+		message += ", último fechamento em " + time.UnixMilli(market.LastClose).UTC().Format(time.RFC3339)
+	}
+	return message
+}
+
+// priceHandler atende GET /prices?pair=EUR/USD e, em lote,
+// GET /prices?pairs=EUR/USD,BTC/USD. Para ações, índices, ETFs e futuros,
+// symbol= e symbols= são aceitos como sinônimos, ex.: /prices?symbol=AAPL
+func priceHandler(w http.ResponseWriter, r *http.Request) {
+	caller, ok := authorizeAPICall(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	params, err := parseQuoteParams(query.Get("fields"), query.Get("max_age"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pairs := query.Get("pairs")
+	if pairs == "" {
+		pairs = query.Get("symbols")
+	}
+	if pairs != "" {
+		writeBatch(w, caller, strings.Split(pairs, ","), params)
+		return
+	}
+
+	pair := query.Get("pair")
+	if pair == "" {
+		pair = query.Get("symbol")
+	}
+	if pair == "" {
+		http.Error(w, "Par de moedas é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	response, qerr := buildQuote(pair, params)
+	if qerr != nil {
+		http.Error(w, qerr.Error, qerr.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+	caller.charge(1)
+}
+
+type batchRequest struct {
+	Pairs   []string `json:"pairs"`
+	Symbols []string `json:"symbols"`
+	Fields  string   `json:"fields"`
+	MaxAge  string   `json:"max_age"`
+}
+
+// batchPriceHandler atende POST /prices/batch com
+// {"pairs":["EUR/USD","BTC/USD"],"fields":"bid,ask","max_age":"30s"}
+func batchPriceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	caller, ok := authorizeAPICall(w, r)
+	if !ok {
+		return
+	}
+
+	var request batchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&request); err != nil {
+		http.Error(w, "Corpo da requisição inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	params, err := parseQuoteParams(request.Fields, request.MaxAge)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeBatch(w, caller, append(request.Pairs, request.Symbols...), params)
+}
+
+// batchCost retorna quantas chamadas um lote com n cotações consome.
+func batchCost(n int) int {
+	return (n + batchPairsPerCall - 1) / batchPairsPerCall
+}
+
+// writeBatch responde com as cotações dos pares e os erros de cada par. Só as
+// cotações retornadas são contabilizadas, em blocos de batchPairsPerCall.
+func writeBatch(w http.ResponseWriter, caller *apiCaller, pairs []string, params quoteParams) {
+	unique := make([]string, 0, len(pairs))
+	seen := make(map[string]bool)
+	for _, pair := range pairs {
+		pair = strings.TrimSpace(pair)
+		if pair == "" || seen[pair] {
+			continue
+		}
+		seen[pair] = true
+		unique = append(unique, pair)
+	}
+	if len(unique) == 0 {
+		http.Error(w, "Informe ao menos um par de moedas", http.StatusBadRequest)
+		return
+	}
+	if len(unique) > maxBatchPairs {
+		http.Error(w, fmt.Sprintf("Máximo de %d pares por requisição", maxBatchPairs), http.StatusBadRequest)
+		return
+	}
+	if batchCost(len(unique)) > caller.remaining() {
+		http.Error(w, "API usage limit exceeded", http.StatusTooManyRequests)
+		return
+	}
+
+	quotes := make(map[string]interface{})
+	errs := make(map[string]*quoteError)
+	for _, pair := range unique {
+		quote, qerr := buildQuote(pair, params)
+		if qerr != nil {
+			errs[pair] = qerr
+			continue
+		}
+		quotes[pair] = quote
+	}
+
+	calls := batchCost(len(quotes))
+	response := map[string]interface{}{
+		"quotes": quotes,
+		"calls":  calls,
+	}
+	if len(errs) > 0 {
+		response["errors"] = errs
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+	if calls > 0 {
+		caller.charge(calls)
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"wsaetherfy/currency"
+)
+
+const (
+	// Quantidade máxima de pares por conexão em /stream
+	maxStreamPairs = 50
+	// Quantidade máxima de ticks reenviados por par ao retomar a conexão
+	streamReplayLimit = 1000
+	// Intervalo dos comentários enviados para manter a conexão aberta em proxies
+	streamPingInterval = 15 * time.Second
+	// Espera sugerida ao cliente antes de reconectar, em milissegundos
+	streamRetry = 3000
+)
+
+// streamCursor guarda a última sequência enviada de cada par e o horário do
+// tick mais recente enviado. Ele é enviado como id dos eventos, no formato
+// "<epoch>|<horário>|EUR/USD:12|BTC/USD:7", e volta no header Last-Event-ID
+// quando o cliente reconecta. As sequências só valem no mesmo epoch do
+// histórico; depois de um reinício a retomada usa o horário.
+type streamCursor struct {
+	seqs      map[string]uint64
+	timestamp int64
+}
+
+func newStreamCursor() streamCursor {
+	return streamCursor{seqs: make(map[string]uint64)}
+}
+
+func (c streamCursor) id(epoch int64) string {
+	pairs := make([]string, 0, len(c.seqs))
+	for pair := range c.seqs {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d|%d", epoch, c.timestamp)
+	for _, pair := range pairs {
+		fmt.Fprintf(&b, "|%s:%d", pair, c.seqs[pair])
+	}
+	return b.String()
+}
+
+// parseStreamCursor interpreta o Last-Event-ID. Ids malformados são
+// ignorados; nos de outro epoch do histórico, como os de antes de um
+// reinício, só o horário é mantido.
+func parseStreamCursor(id string, epoch int64) streamCursor {
+	cursor := newStreamCursor()
+	parts := strings.Split(id, "|")
+	if len(parts) < 2 {
+		return cursor
+	}
+	timestamp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return cursor
+	}
+	if parts[0] != strconv.FormatInt(epoch, 10) {
+		cursor.timestamp = timestamp
+		return cursor
+	}
+	for _, part := range parts[2:] {
+		i := strings.LastIndex(part, ":")
+		if i <= 0 {
+			return newStreamCursor()
+		}
+		seq, err := strconv.ParseUint(part[i+1:], 10, 64)
+		if err != nil {
+			return newStreamCursor()
+		}
+		cursor.seqs[part[:i]] = seq
+	}
+	cursor.timestamp = timestamp
+	return cursor
+}
+
+// advance registra o evento no cursor e informa se ele ainda não foi enviado.
+// Taxas sintéticas não têm sequência e ficam fora do cursor.
+func (c streamCursor) advance(event streamEvent) bool {
+	if event.cross {
+		return true
+	}
+	if last, found := c.seqs[event.pair]; found && event.seq <= last {
+		return false
+	}
+	c.seqs[event.pair] = event.seq
+	return true
+}
+
+// streamEvent é um tick a ser enviado ao cliente.
+type streamEvent struct {
+	pair    string
+	seq     uint64
+	data    currency.PriceData
+	session string
+	// Taxa sintética, calculada a partir de outros pares
+	cross bool
+}
+
+// writeStreamEvents escreve os ticks no formato text/event-stream, com o
+// mesmo conteúdo das mensagens de preço do /ws. Ticks já enviados são
+// descartados. Só o último evento de cada lote leva o id, já que o cliente
+// guarda apenas o último id recebido.
+func writeStreamEvents(w http.ResponseWriter, flusher http.Flusher, sent *streamCursor, epoch int64, events []streamEvent, fields []string) error {
+	var pending []streamEvent
+	for _, event := range events {
+		if sent.advance(event) {
+			pending = append(pending, event)
+		}
+	}
+	for i, event := range pending {
+		price := event.data.Fields(fields)
+		price["type"] = "price"
+		price["pair"] = event.pair
+		price["seq"] = event.seq
+		price["session"] = event.session
+		data, err := json.Marshal(price)
+		if err != nil {
+			return err
+		}
+		if event.data.Timestamp > sent.timestamp {
+			sent.timestamp = event.data.Timestamp
+		}
+		if i == len(pending)-1 {
+			fmt.Fprintf(w, "id: %s\n", sent.id(epoch))
+		}
+		if _, err := fmt.Fprintf(w, "event: price\ndata: %s\n\n", data); err != nil {
+			return err
+		}
+	}
+	flusher.Flush()
+	return nil
+}
+
+// streamHandler atende GET /stream?pairs=EUR/USD,BTC/USD&fields= com Server-Sent
+// Events, para clientes atrás de proxies que não suportam WebSocket. Ao
+// reconectar com Last-Event-ID, os ticks perdidos são reenviados a partir do
+// histórico de preços. Pares sintéticos, como no /ws, são enviados só ao
+// vivo, sem reenvio.
+func streamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := verifyAPIKey(w, r); !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	fields, err := currency.ParseFields(query.Get("fields"))
+	if err != nil {
+		http.Error(w, "Parâmetro fields inválido: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	value := query.Get("pairs")
+	if value == "" {
+		value = query.Get("symbols")
+	}
+	var pairs []string
+	var crossPairs []string
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" || seen[pair] {
+			continue
+		}
+		seen[pair] = true
+		if _, exists := currency.GetCurrencyCode(pair); exists {
+			pairs = append(pairs, pair)
+			continue
+		}
+		if !currency.IsCrossPair(pair) {
+			http.Error(w, "Par de moedas não encontrado: "+pair, http.StatusNotFound)
+			return
+		}
+		crossPairs = append(crossPairs, pair)
+	}
+	if len(pairs)+len(crossPairs) == 0 {
+		http.Error(w, "Informe ao menos um par de moedas", http.StatusBadRequest)
+		return
+	}
+	if len(pairs)+len(crossPairs) > maxStreamPairs {
+		http.Error(w, fmt.Sprintf("Máximo de %d pares por conexão", maxStreamPairs), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming não suportado", http.StatusInternalServerError)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = query.Get("last_event_id")
+	}
+	epoch := currency.HistoryEpoch()
+	cursor := parseStreamCursor(lastEventID, epoch)
+
+	// Cada par começa na sequência do Last-Event-ID ou, sem ela, no último
+	// tick atual, para que o id de retomada cubra todos os pares desde já
+	sent := newStreamCursor()
+	sent.timestamp = cursor.timestamp
+	resumeByTime := make(map[string]bool)
+	for _, pair := range pairs {
+		if seq, found := cursor.seqs[pair]; found {
+			sent.seqs[pair] = seq
+		} else if cursor.timestamp > 0 {
+			resumeByTime[pair] = true
+		} else if seq, found := currency.GetLastSeq(pair); found {
+			sent.seqs[pair] = seq
+		}
+	}
+
+	// Assina antes de ler o histórico para não perder ticks entre os dois; os
+	// repetidos são descartados pela sequência
+	sub := currency.NewSubscriber()
+	defer sub.Close()
+	sub.Subscribe(pairs...)
+	sub.Subscribe(crossPairs...)
+	for _, pair := range crossPairs {
+		currency.WatchCrossPair(pair)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+
+	// Reenvia, em ordem cronológica, os ticks posteriores ao cursor
+	var backlog []streamEvent
+	for _, pair := range pairs {
+		var ticks []currency.PriceData
+		var first uint64
+		if resumeByTime[pair] {
+			ticks, first, _ = currency.GetSinceTime(pair, cursor.timestamp, streamReplayLimit)
+		} else if after, found := sent.seqs[pair]; found {
+			ticks, first, _ = currency.GetSince(pair, after, streamReplayLimit)
+		}
+		for i, data := range ticks {
+			backlog = append(backlog, streamEvent{pair: pair, seq: first + uint64(i), data: data, session: currency.SessionOf(pair, data, nil)})
+		}
+	}
+	sort.SliceStable(backlog, func(i, j int) bool {
+		return backlog[i].data.Timestamp < backlog[j].data.Timestamp
+	})
+	if err := writeStreamEvents(w, flusher, &sent, epoch, backlog, fields); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case update, ok := <-sub.C:
+			if !ok {
+				fmt.Fprint(w, "event: error\ndata: {\"error\":\"Conexão encerrada: cliente não acompanhou o fluxo de preços\"}\n\n")
+				flusher.Flush()
+				return
+			}
+			// Junta os ticks já disponíveis em um único lote
+			events := []streamEvent{{pair: update.Pair, seq: update.Seq, data: update.Data, session: update.Session, cross: update.Cross != nil}}
+			for drained := false; !drained; {
+				select {
+				case update, ok := <-sub.C:
+					if !ok {
+						drained = true
+						break
+					}
+					events = append(events, streamEvent{pair: update.Pair, seq: update.Seq, data: update.Data, session: update.Session, cross: update.Cross != nil})
+				default:
+					drained = true
+				}
+			}
+			if err := writeStreamEvents(w, flusher, &sent, epoch, events, fields); err != nil {
+				return
+			}
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
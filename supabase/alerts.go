@@ -0,0 +1,128 @@
+package supabase
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+	"wsaetherfy/alerts"
+
+	supa "github.com/supabase-community/supabase-go"
+)
+
+// Tabelas usadas pelos alertas:
+//
+//	alerts (id text primary key, user_id text, pair text, condition text,
+//	        value float8, window text, webhook_url text, secret text,
+//	        once bool, active bool, created_at timestamptz, triggered_at timestamptz)
+//	alert_deliveries (id bigserial primary key, alert_id text, user_id text,
+//	        event_id text, attempt int, status_code int, error text,
+//	        success bool, created_at timestamptz)
+
+// Quantidade máxima de entregas retornadas por alerta
+const maxDeliveries = 100
+
+// execute roda a consulta e a repete uma vez se a sessão tiver expirado.
+func execute(client *supa.Client, query func() ([]byte, int64, error)) ([]byte, error) {
+	data, _, err := query()
+	if err != nil && err.Error() == "JWT expired" {
+		if err := refreshSession(client); err != nil {
+			return nil, fmt.Errorf("erro ao renovar a sessão: %v", err)
+		}
+		data, _, err = query()
+	}
+	return data, err
+}
+
+func decodeAlerts(data []byte) ([]alerts.Alert, error) {
+	var result []alerts.Alert
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar a resposta JSON: %v", err)
+	}
+	return result, nil
+}
+
+func GetAlertsByUserId(client *supa.Client, userId string) ([]alerts.Alert, error) {
+	data, err := execute(client, func() ([]byte, int64, error) {
+		return client.From("alerts").Select("*", "exact", false).Eq("user_id", userId).Execute()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar a tabela alerts: %v", err)
+	}
+	return decodeAlerts(data)
+}
+
+func GetActiveAlerts(client *supa.Client) ([]alerts.Alert, error) {
+	data, err := execute(client, func() ([]byte, int64, error) {
+		return client.From("alerts").Select("*", "exact", false).Eq("active", "true").Execute()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar a tabela alerts: %v", err)
+	}
+	return decodeAlerts(data)
+}
+
+func InsertAlert(client *supa.Client, alert alerts.Alert) error {
+	_, err := execute(client, func() ([]byte, int64, error) {
+		return client.From("alerts").Insert(alert, false, "", "minimal", "").Execute()
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao inserir na tabela alerts: %v", err)
+	}
+	return nil
+}
+
+// DeleteAlert remove o alerta do usuário e informa se alguma linha foi
+// removida.
+func DeleteAlert(client *supa.Client, userId, id string) (bool, error) {
+	data, err := execute(client, func() ([]byte, int64, error) {
+		return client.From("alerts").Delete("representation", "").Eq("id", id).Eq("user_id", userId).Execute()
+	})
+	if err != nil {
+		return false, fmt.Errorf("erro ao remover da tabela alerts: %v", err)
+	}
+	deleted, err := decodeAlerts(data)
+	if err != nil {
+		return false, err
+	}
+	return len(deleted) > 0, nil
+}
+
+func UpdateAlertTriggered(client *supa.Client, id string, at time.Time, active bool) error {
+	_, err := execute(client, func() ([]byte, int64, error) {
+		return client.From("alerts").Update(map[string]interface{}{
+			"triggered_at": at.UTC(),
+			"active":       active,
+		}, "minimal", "").Eq("id", id).Execute()
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao atualizar a tabela alerts: %v", err)
+	}
+	return nil
+}
+
+func InsertAlertDelivery(client *supa.Client, delivery alerts.Delivery) error {
+	_, err := execute(client, func() ([]byte, int64, error) {
+		return client.From("alert_deliveries").Insert(delivery, false, "", "minimal", "").Execute()
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao inserir na tabela alert_deliveries: %v", err)
+	}
+	return nil
+}
+
+func GetAlertDeliveries(client *supa.Client, userId, alertId string) ([]alerts.Delivery, error) {
+	data, err := execute(client, func() ([]byte, int64, error) {
+		return client.From("alert_deliveries").Select("*", "exact", false).
+			Eq("alert_id", alertId).Eq("user_id", userId).
+			Order("created_at", nil).Limit(maxDeliveries, "").Execute()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao consultar a tabela alert_deliveries: %v", err)
+	}
+
+	var result []alerts.Delivery
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar a resposta JSON: %v", err)
+	}
+	return result, nil
+}
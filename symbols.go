@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"wsaetherfy/currency"
+)
+
+// symbolsHandler atende GET /symbols?asset_class=fx|crypto
+func symbolsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, ok := verifyAPIKey(w, r); !ok {
+		return
+	}
+
+	class := r.URL.Query().Get("asset_class")
+	symbols := currency.Symbols(class)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"symbols": symbols,
+		"count":   len(symbols),
+	})
+}
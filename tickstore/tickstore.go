@@ -0,0 +1,231 @@
+package tickstore
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"wsaetherfy/currency"
+)
+
+const (
+	segmentPrefix = "ticks-"
+	segmentSuffix = ".log"
+	segmentLayout = "20060102"
+	flushInterval = time.Second
+)
+
+// record é a linha gravada em cada segmento.
+type record struct {
+	Pair string `json:"pair"`
+	currency.PriceData
+}
+
+// FileStore persiste os ticks em segmentos diários append-only (um JSON por
+// linha) dentro de um diretório local. Segmentos mais antigos que a retenção
+// são apagados na rotação.
+type FileStore struct {
+	sync.Mutex
+	dir       string
+	retention time.Duration
+	day       string
+	file      *os.File
+	writer    *bufio.Writer
+	done      chan struct{}
+}
+
+// Open abre (ou cria) o diretório de segmentos. Uma retenção zero mantém os
+// segmentos para sempre.
+func Open(dir string, retention time.Duration) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("erro ao criar o diretório %s: %v", dir, err)
+	}
+	fs := &FileStore{
+		dir:       dir,
+		retention: retention,
+		done:      make(chan struct{}),
+	}
+	go fs.flushLoop()
+	return fs, nil
+}
+
+func segmentDay(timestamp int64) string {
+	return time.UnixMilli(timestamp).UTC().Format(segmentLayout)
+}
+
+func (fs *FileStore) segmentPath(day string) string {
+	return filepath.Join(fs.dir, segmentPrefix+day+segmentSuffix)
+}
+
+// segments retorna os dias dos segmentos existentes em ordem cronológica.
+func (fs *FileStore) segments() ([]string, error) {
+	entries, err := os.ReadDir(fs.dir)
+	if err != nil {
+		return nil, err
+	}
+	var days []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		days = append(days, strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix))
+	}
+	sort.Strings(days)
+	return days, nil
+}
+
+// rotate fecha o segmento atual e abre o do dia informado. Deve ser chamada
+// com o lock.
+func (fs *FileStore) rotate(day string) error {
+	if err := fs.closeSegment(); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(fs.segmentPath(day), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir o segmento %s: %v", day, err)
+	}
+	fs.day = day
+	fs.file = file
+	fs.writer = bufio.NewWriter(file)
+	fs.prune()
+	return nil
+}
+
+// prune apaga os segmentos fora da retenção.
+func (fs *FileStore) prune() {
+	if fs.retention <= 0 {
+		return
+	}
+	oldest := time.Now().Add(-fs.retention).UTC().Format(segmentLayout)
+	days, err := fs.segments()
+	if err != nil {
+		log.Printf("Erro ao listar segmentos: %v", err)
+		return
+	}
+	for _, day := range days {
+		if day >= oldest || day == fs.day {
+			continue
+		}
+		if err := os.Remove(fs.segmentPath(day)); err != nil {
+			log.Printf("Erro ao apagar o segmento %s: %v", day, err)
+		}
+	}
+}
+
+func (fs *FileStore) closeSegment() error {
+	if fs.file == nil {
+		return nil
+	}
+	if err := fs.writer.Flush(); err != nil {
+		return err
+	}
+	err := fs.file.Close()
+	fs.file = nil
+	fs.writer = nil
+	return err
+}
+
+func (fs *FileStore) Append(pair string, data currency.PriceData) error {
+	line, err := json.Marshal(record{Pair: pair, PriceData: data})
+	if err != nil {
+		return err
+	}
+
+	fs.Lock()
+	defer fs.Unlock()
+	if day := segmentDay(data.Timestamp); fs.file == nil || day > fs.day {
+		if err := fs.rotate(day); err != nil {
+			return err
+		}
+	}
+	if _, err := fs.writer.Write(line); err != nil {
+		return err
+	}
+	return fs.writer.WriteByte('\n')
+}
+
+func (fs *FileStore) Load(since int64, fn func(pair string, data currency.PriceData)) error {
+	fs.Lock()
+	defer fs.Unlock()
+	if fs.writer != nil {
+		if err := fs.writer.Flush(); err != nil {
+			return err
+		}
+	}
+
+	days, err := fs.segments()
+	if err != nil {
+		return err
+	}
+	first := segmentDay(since)
+	for _, day := range days {
+		if day < first {
+			continue
+		}
+		if err := fs.loadSegment(day, since, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fs *FileStore) loadSegment(day string, since int64, fn func(pair string, data currency.PriceData)) error {
+	file, err := os.Open(fs.segmentPath(day))
+	if err != nil {
+		return fmt.Errorf("erro ao abrir o segmento %s: %v", day, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// Uma linha truncada indica uma escrita interrompida; ignora e segue
+			log.Printf("Linha inválida no segmento %s: %v", day, err)
+			continue
+		}
+		if rec.Timestamp < since {
+			continue
+		}
+		fn(rec.Pair, rec.PriceData)
+	}
+	return scanner.Err()
+}
+
+func (fs *FileStore) flushLoop() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			fs.Lock()
+			if fs.writer != nil {
+				if err := fs.writer.Flush(); err != nil {
+					log.Printf("Erro ao gravar segmento %s: %v", fs.day, err)
+				}
+			}
+			fs.Unlock()
+		case <-fs.done:
+			return
+		}
+	}
+}
+
+func (fs *FileStore) Close() error {
+	fs.Lock()
+	defer fs.Unlock()
+	select {
+	case <-fs.done:
+		return nil
+	default:
+		close(fs.done)
+	}
+	return fs.closeSegment()
+}
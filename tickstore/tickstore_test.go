@@ -0,0 +1,56 @@
+package tickstore
+
+import (
+	"testing"
+	"time"
+	"wsaetherfy/currency"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := 24 * time.Hour.Milliseconds()
+	ticks := []currency.PriceData{
+		{Price: 1.1, Timestamp: 10},
+		{Price: 1.2, Timestamp: day + 10},
+		{Price: 1.3, Timestamp: day + 20},
+	}
+	for _, tick := range ticks {
+		if err := fs.Append("EUR/USD", tick); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	var got []currency.PriceData
+	err = reopened.Load(day+15, func(pair string, data currency.PriceData) {
+		if pair != "EUR/USD" {
+			t.Errorf("unexpected pair %q", pair)
+		}
+		got = append(got, data)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != ticks[2] {
+		t.Fatalf("unexpected ticks: %+v", got)
+	}
+
+	got = nil
+	reopened.Load(0, func(pair string, data currency.PriceData) { got = append(got, data) })
+	if len(got) != 3 {
+		t.Fatalf("expected 3 ticks, got %+v", got)
+	}
+}
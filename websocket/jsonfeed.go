@@ -0,0 +1,347 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wsaetherfy/yatickerpb"
+
+	"github.com/gorilla/websocket"
+)
+
+// JSONFeedConfig descreve como conversar com uma exchange que publica
+// cotações em JSON sobre WebSocket.
+type JSONFeedConfig struct {
+	URL string
+	// Mensagem de assinatura; {{params}} é substituído pela lista JSON dos
+	// símbolos formatados por ParamTemplate
+	SubscribeTemplate   string
+	UnsubscribeTemplate string
+	// Formato de cada símbolo na assinatura, com {{symbol}} e {{lower}};
+	// vazio usa o próprio símbolo
+	ParamTemplate string
+	// Caminhos (separados por ponto, ex.: "data.p") dos campos nas mensagens
+	SymbolField string
+	PriceField  string
+	TimeField   string
+	BidField    string
+	AskField    string
+	SizeField   string
+	// Indica se o horário das mensagens está em segundos em vez de milissegundos
+	TimeInSeconds bool
+}
+
+// JSONFeed é um Provider genérico para fontes JSON-over-WebSocket. Mensagens
+// sem símbolo ou preço (confirmações, heartbeats) são ignoradas.
+type JSONFeed struct {
+	cfg JSONFeedConfig
+	// mu protege ws, connected e subs, e serializa as escritas na conexão
+	mu        sync.Mutex
+	ws        *websocket.Conn
+	subs      []string
+	connected bool
+	policy    ReconnectPolicy
+	// lifetime é cancelado por Close e interrompe leitura, espera e discagem
+	lifetime context.Context
+	cancel   context.CancelFunc
+}
+
+func NewJSONFeed(cfg JSONFeedConfig) *JSONFeed {
+	lifetime, cancel := context.WithCancel(context.Background())
+	return &JSONFeed{cfg: cfg, policy: DefaultReconnectPolicy, lifetime: lifetime, cancel: cancel}
+}
+
+// SetReconnectPolicy define como a leitura reconecta quando a conexão cai.
+// Deve ser chamada antes de Ticks.
+func (jf *JSONFeed) SetReconnectPolicy(policy ReconnectPolicy) {
+	jf.policy = policy
+}
+
+func (jf *JSONFeed) Connect() error {
+	if jf.stopped() {
+		return ErrClosed
+	}
+	ws, _, err := websocket.DefaultDialer.DialContext(jf.lifetime, jf.cfg.URL, nil)
+	if err != nil {
+		fmt.Println("Fail to Dial: ", err)
+		return err
+	}
+
+	jf.mu.Lock()
+	defer jf.mu.Unlock()
+	if jf.stopped() {
+		ws.Close()
+		return ErrClosed
+	}
+	if jf.connected {
+		jf.ws.Close()
+	}
+	jf.ws = ws
+	jf.connected = true
+	return nil
+}
+
+// isConnected informa se há uma conexão aberta com a fonte.
+func (jf *JSONFeed) isConnected() bool {
+	jf.mu.Lock()
+	defer jf.mu.Unlock()
+	return jf.connected
+}
+
+// disconnect fecha a conexão informada se ela ainda for a atual.
+func (jf *JSONFeed) disconnect(ws *websocket.Conn) {
+	jf.mu.Lock()
+	defer jf.mu.Unlock()
+	ws.Close()
+	if jf.ws == ws {
+		jf.connected = false
+	}
+}
+
+// Close encerra a conexão e o leitor iniciado por Ticks, que fecha o canal.
+func (jf *JSONFeed) Close() error {
+	jf.cancel()
+	jf.mu.Lock()
+	defer jf.mu.Unlock()
+	if !jf.connected {
+		return nil
+	}
+	jf.connected = false
+	return jf.ws.Close()
+}
+
+func (jf *JSONFeed) stopped() bool {
+	return jf.lifetime.Err() != nil
+}
+
+// message monta a mensagem de (des)assinatura a partir do template.
+func (jf *JSONFeed) message(template string, subs []string) ([]byte, error) {
+	params := make([]string, 0, len(subs))
+	for _, sub := range subs {
+		param := jf.cfg.ParamTemplate
+		if param == "" {
+			param = "{{symbol}}"
+		}
+		param = strings.ReplaceAll(param, "{{symbol}}", sub)
+		param = strings.ReplaceAll(param, "{{lower}}", strings.ToLower(sub))
+		params = append(params, param)
+	}
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.ReplaceAll(template, "{{params}}", string(encoded))), nil
+}
+
+func (jf *JSONFeed) write(template string, subs []string) error {
+	if template == "" || len(subs) == 0 {
+		return nil
+	}
+	message, err := jf.message(template, subs)
+	if err != nil {
+		fmt.Println("Fail to Build Message: ", err)
+		return err
+	}
+	jf.mu.Lock()
+	defer jf.mu.Unlock()
+	if !jf.connected {
+		return fmt.Errorf("json feed: não conectado")
+	}
+	if err := jf.ws.WriteMessage(websocket.TextMessage, message); err != nil {
+		fmt.Println("Fail to Write Message: ", err)
+		return err
+	}
+	return nil
+}
+
+// Subs retorna os símbolos atualmente assinados.
+func (jf *JSONFeed) Subs() []string {
+	jf.mu.Lock()
+	defer jf.mu.Unlock()
+	return append([]string(nil), jf.subs...)
+}
+
+func (jf *JSONFeed) Subscribe(subs ...string) error {
+	if !jf.isConnected() {
+		if err := jf.Connect(); err != nil {
+			fmt.Println("Fail to connect to WS: ", err)
+			return err
+		}
+	}
+	jf.mu.Lock()
+	for _, sub := range subs {
+		jf.subs = removeSubs(jf.subs, sub)
+		jf.subs = append(jf.subs, sub)
+	}
+	jf.mu.Unlock()
+	return jf.write(jf.cfg.SubscribeTemplate, jf.Subs())
+}
+
+func (jf *JSONFeed) Unsubscribe(subs ...string) error {
+	jf.mu.Lock()
+	jf.subs = removeSubs(jf.subs, subs...)
+	connected := jf.connected
+	jf.mu.Unlock()
+	if !connected {
+		return nil
+	}
+	return jf.write(jf.cfg.UnsubscribeTemplate, subs)
+}
+
+func (jf *JSONFeed) Ticks() (<-chan *yatickerpb.Yaticker, error) {
+	if !jf.isConnected() {
+		if err := jf.Connect(); err != nil {
+			fmt.Println("Fail to connect to WS: ", err)
+			return nil, err
+		}
+	}
+	output := make(chan *yatickerpb.Yaticker, 10)
+	go func() {
+		defer close(output)
+		for {
+			jf.mu.Lock()
+			ws := jf.ws
+			jf.mu.Unlock()
+
+			err := jf.readMessages(ws, output)
+			if jf.stopped() {
+				return
+			}
+			fmt.Println("Fail to Read Message: ", err)
+			jf.disconnect(ws)
+			if !jf.reconnect() {
+				return
+			}
+			fmt.Println("WS Conection Restored")
+		}
+	}()
+	return output, nil
+}
+
+// reconnect tenta restabelecer a conexão e reassinar todos os símbolos
+// seguindo a política de reconexão. Retorna false quando as tentativas se
+// esgotam ou o JSONFeed é fechado.
+func (jf *JSONFeed) reconnect() bool {
+	for attempt := 1; jf.policy.MaxAttempts == 0 || attempt <= jf.policy.MaxAttempts; attempt++ {
+		select {
+		case <-time.After(jf.policy.Delay(attempt)):
+		case <-jf.lifetime.Done():
+			return false
+		}
+
+		if err := jf.Connect(); err != nil {
+			if jf.stopped() {
+				return false
+			}
+			fmt.Printf("Fail to reconnect to WS (attempt %d): %v\n", attempt, err)
+			continue
+		}
+		if err := jf.write(jf.cfg.SubscribeTemplate, jf.Subs()); err != nil {
+			jf.mu.Lock()
+			ws := jf.ws
+			jf.mu.Unlock()
+			jf.disconnect(ws)
+			continue
+		}
+		return true
+	}
+	fmt.Println("Giving up reconnecting to WS")
+	return false
+}
+
+func (jf *JSONFeed) readMessages(ws *websocket.Conn, output chan *yatickerpb.Yaticker) error {
+	for {
+		_, message, err := ws.ReadMessage()
+		if err != nil {
+			return err
+		}
+		for _, ticker := range jf.decode(message) {
+			select {
+			case output <- ticker:
+			case <-jf.lifetime.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// decode converte uma mensagem (objeto ou lista de objetos) em ticks.
+func (jf *JSONFeed) decode(message []byte) []*yatickerpb.Yaticker {
+	decoder := json.NewDecoder(strings.NewReader(string(message)))
+	decoder.UseNumber()
+	var body interface{}
+	if err := decoder.Decode(&body); err != nil {
+		fmt.Println("Fail to Unmarshal Message: ", err)
+		return nil
+	}
+
+	items, ok := body.([]interface{})
+	if !ok {
+		items = []interface{}{body}
+	}
+
+	var tickers []*yatickerpb.Yaticker
+	for _, item := range items {
+		symbol, ok := lookupField(item, jf.cfg.SymbolField).(string)
+		if !ok || symbol == "" {
+			continue
+		}
+		price, ok := toFloat(lookupField(item, jf.cfg.PriceField))
+		if !ok {
+			continue
+		}
+		ticker := &yatickerpb.Yaticker{Id: symbol, Price: float32(price)}
+		if t, ok := toFloat(lookupField(item, jf.cfg.TimeField)); ok {
+			if jf.cfg.TimeInSeconds {
+				t *= 1000
+			}
+			ticker.Time = int64(t)
+		} else {
+			ticker.Time = time.Now().UnixMilli()
+		}
+		if bid, ok := toFloat(lookupField(item, jf.cfg.BidField)); ok {
+			ticker.Bid = float32(bid)
+		}
+		if ask, ok := toFloat(lookupField(item, jf.cfg.AskField)); ok {
+			ticker.Ask = float32(ask)
+		}
+		if size, ok := toFloat(lookupField(item, jf.cfg.SizeField)); ok {
+			ticker.LastSize = int64(size)
+		}
+		tickers = append(tickers, ticker)
+	}
+	return tickers
+}
+
+// lookupField percorre um caminho separado por pontos em objetos JSON.
+func lookupField(value interface{}, path string) interface{} {
+	if path == "" {
+		return nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// toFloat aceita números e strings numéricas, comuns em APIs de exchanges.
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestJSONFeedDecode(t *testing.T) {
+	jf := NewJSONFeed(JSONFeedConfig{
+		SymbolField: "data.s",
+		PriceField:  "data.p",
+		TimeField:   "data.T",
+		SizeField:   "data.q",
+	})
+
+	tickers := jf.decode([]byte(`{"stream":"btcusdt@trade","data":{"s":"BTCUSDT","p":"64000.50","q":"2","T":1700000000000}}`))
+	if len(tickers) != 1 {
+		t.Fatalf("expected 1 ticker, got %d", len(tickers))
+	}
+	ticker := tickers[0]
+	if ticker.Id != "BTCUSDT" || ticker.Price != 64000.5 || ticker.Time != 1700000000000 || ticker.LastSize != 2 {
+		t.Fatalf("unexpected ticker: %+v", ticker)
+	}
+
+	if tickers := jf.decode([]byte(`{"result":null,"id":1}`)); len(tickers) != 0 {
+		t.Fatalf("expected acknowledgement to be ignored, got %+v", tickers)
+	}
+
+	message, err := NewJSONFeed(JSONFeedConfig{ParamTemplate: "{{lower}}@trade"}).message(`{"method":"SUBSCRIBE","params":{{params}}}`, []string{"BTCUSDT"})
+	if err != nil || string(message) != `{"method":"SUBSCRIBE","params":["btcusdt@trade"]}` {
+		t.Fatalf("unexpected subscribe message %s (%v)", message, err)
+	}
+}
+
+func TestJSONFeedReconnects(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	var connections int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		n := atomic.AddInt32(&connections, 1)
+		if _, _, err := ws.ReadMessage(); err != nil {
+			return
+		}
+		ws.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"s":"BTCUSDT","p":"%d"}`, n)))
+		// Derruba as primeiras conexões para forçar a reconexão
+		if n < 3 {
+			return
+		}
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	jf := NewJSONFeed(JSONFeedConfig{
+		URL:                 "ws" + strings.TrimPrefix(server.URL, "http"),
+		SubscribeTemplate:   `{"subscribe":{{params}}}`,
+		UnsubscribeTemplate: `{"unsubscribe":{{params}}}`,
+		SymbolField:         "s",
+		PriceField:          "p",
+	})
+	jf.SetReconnectPolicy(ReconnectPolicy{InitialDelay: time.Millisecond, Multiplier: 1})
+	defer jf.Close()
+	if err := jf.Subscribe("BTCUSDT"); err != nil {
+		t.Fatal(err)
+	}
+	ticks, err := jf.Ticks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assinaturas concorrentes com a reconexão
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			jf.Subscribe("ETHUSDT")
+			jf.Unsubscribe("ETHUSDT")
+		}
+	}()
+
+	for want := float32(1); want <= 3; want++ {
+		select {
+		case ticker, ok := <-ticks:
+			if !ok {
+				t.Fatal("ticks channel closed")
+			}
+			if ticker.Price != want {
+				t.Fatalf("expected price %v, got %+v", want, ticker)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for tick %v", want)
+		}
+	}
+	<-done
+}
@@ -0,0 +1,45 @@
+package websocket
+
+import "wsaetherfy/yatickerpb"
+
+// Provider é uma fonte de cotações em tempo real. Os ticks de todas as
+// fontes são entregues no formato do Yaticker, com Id igual ao símbolo
+// assinado no provedor.
+type Provider interface {
+	Connect() error
+	Subscribe(subs ...string) error
+	Unsubscribe(subs ...string) error
+	Ticks() (<-chan *yatickerpb.Yaticker, error)
+	Close() error
+}
+
+// StateNotifier é implementado pelos provedores que informam as mudanças de
+// estado da conexão.
+type StateNotifier interface {
+	States() <-chan StateEvent
+}
+
+var (
+	_ StateNotifier = (*YahooFinance)(nil)
+	_ Provider      = (*YahooFinance)(nil)
+	_ Provider      = (*JSONFeed)(nil)
+	_ Provider      = (*Replay)(nil)
+)
+
+// removeSubs remove os símbolos informados da lista.
+func removeSubs(list []string, subs ...string) []string {
+	result := list[:0]
+	for _, s := range list {
+		keep := true
+		for _, sub := range subs {
+			if s == sub {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+package websocket
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ReconnectPolicy controla as tentativas de reconexão com o upstream. O
+// intervalo começa em InitialDelay e é multiplicado por Multiplier a cada
+// falha, até MaxDelay, com uma variação aleatória de ±Jitter (fração do
+// intervalo). MaxAttempts zero tenta para sempre.
+type ReconnectPolicy struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	Jitter       float64
+	MaxAttempts  int
+}
+
+var DefaultReconnectPolicy = ReconnectPolicy{
+	InitialDelay: time.Second,
+	MaxDelay:     time.Minute,
+	Multiplier:   2,
+	Jitter:       0.2,
+}
+
+// Delay retorna a espera antes da tentativa informada (a partir de 1).
+func (p ReconnectPolicy) Delay(attempt int) time.Duration {
+	delay := float64(p.InitialDelay) * math.Pow(p.Multiplier, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// State é o estado da conexão com o upstream.
+type State int
+
+const (
+	StateConnected State = iota
+	StateDisconnected
+	StateReconnecting
+	StateGaveUp
+	StateClosed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateGaveUp:
+		return "gave_up"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// StateEvent é emitido a cada mudança de estado da conexão. Attempt indica a
+// tentativa de reconexão em andamento e Err o erro que causou a mudança.
+type StateEvent struct {
+	State   State
+	Attempt int
+	Err     error
+	At      time.Time
+}
@@ -0,0 +1,184 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	"wsaetherfy/yatickerpb"
+)
+
+// frameRecord é uma linha do arquivo de gravação: o frame bruto recebido do
+// upstream e o horário de chegada em milissegundos.
+type frameRecord struct {
+	At    int64  `json:"at"`
+	Frame string `json:"frame"`
+}
+
+// Recorder grava os frames brutos do upstream, um JSON por linha, para que
+// possam ser reproduzidos depois pelo Replay.
+type Recorder struct {
+	sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// NewRecorder cria o arquivo de gravação, acrescentando ao final se ele já
+// existir.
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// Record grava o frame com o horário atual.
+func (rec *Recorder) Record(frame []byte) {
+	rec.Lock()
+	defer rec.Unlock()
+	err := rec.encoder.Encode(frameRecord{At: time.Now().UnixMilli(), Frame: string(frame)})
+	if err != nil {
+		fmt.Println("Fail to Record Frame: ", err)
+	}
+}
+
+func (rec *Recorder) Close() error {
+	rec.Lock()
+	defer rec.Unlock()
+	return rec.file.Close()
+}
+
+// Replay é um Provider que reproduz uma gravação do Recorder pelo mesmo
+// caminho de decodificação do YahooFinance. Speed multiplica a velocidade
+// original (1 é tempo real, zero envia tudo sem pausas) e, com Loop, a
+// gravação recomeça ao chegar ao fim, com os horários dos ticks deslocados
+// pela duração da gravação a cada volta.
+type Replay struct {
+	path     string
+	speed    float64
+	loop     bool
+	frames   []frameRecord
+	mu       sync.Mutex
+	subs     map[string]bool
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewReplay(path string, speed float64, loop bool) *Replay {
+	return &Replay{
+		path:  path,
+		speed: speed,
+		loop:  loop,
+		subs:  make(map[string]bool),
+		stop:  make(chan struct{}),
+	}
+}
+
+// Connect carrega a gravação.
+func (rp *Replay) Connect() error {
+	file, err := os.Open(rp.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var frames []frameRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record frameRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			fmt.Println("Fail to Read Recorded Frame: ", err)
+			continue
+		}
+		frames = append(frames, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	rp.frames = frames
+	return nil
+}
+
+// Subscribe restringe a reprodução aos símbolos assinados.
+func (rp *Replay) Subscribe(subs ...string) error {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	for _, sub := range subs {
+		rp.subs[sub] = true
+	}
+	return nil
+}
+
+func (rp *Replay) Unsubscribe(subs ...string) error {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	for _, sub := range subs {
+		delete(rp.subs, sub)
+	}
+	return nil
+}
+
+func (rp *Replay) subscribed(id string) bool {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return rp.subs[id]
+}
+
+func (rp *Replay) Ticks() (<-chan *yatickerpb.Yaticker, error) {
+	if rp.frames == nil {
+		if err := rp.Connect(); err != nil {
+			return nil, err
+		}
+	}
+	output := make(chan *yatickerpb.Yaticker, 10)
+	go func() {
+		defer close(output)
+		// A cada volta os horários avançam a duração da gravação, para que o
+		// tempo dos ticks nunca volte atrás
+		var offset int64
+		for {
+			for i, record := range rp.frames {
+				if i > 0 && rp.speed > 0 {
+					delay := time.Duration(float64(record.At-rp.frames[i-1].At) * float64(time.Millisecond) / rp.speed)
+					select {
+					case <-time.After(delay):
+					case <-rp.stop:
+						return
+					}
+				}
+
+				yaticker, err := decodeFrame([]byte(record.Frame))
+				if err != nil {
+					fmt.Println(err)
+					continue
+				}
+				if !rp.subscribed(yaticker.Id) {
+					continue
+				}
+				if yaticker.Time != 0 {
+					yaticker.Time += offset
+				}
+				select {
+				case output <- yaticker:
+				case <-rp.stop:
+					return
+				}
+			}
+			if !rp.loop || len(rp.frames) == 0 {
+				return
+			}
+			offset += rp.frames[len(rp.frames)-1].At - rp.frames[0].At
+		}
+	}()
+	return output, nil
+}
+
+// Close interrompe a reprodução e fecha o canal de ticks.
+func (rp *Replay) Close() error {
+	rp.stopOnce.Do(func() { close(rp.stop) })
+	return nil
+}
@@ -0,0 +1,97 @@
+package websocket
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"wsaetherfy/websocket/yahootest"
+	"wsaetherfy/yatickerpb"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := yahootest.NewServer(
+		yahootest.Tick("EURUSD=X", 1.25, 1),
+		yahootest.Tick("BTC-USD", 60000, 2),
+		yahootest.Tick("EURUSD=X", 1.5, 3),
+	)
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "frames.jsonl")
+	rec, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	yf := NewWithSubs("EURUSD=X", "BTC-USD")
+	yf.SetURL(server.URL())
+	yf.SetRecorder(rec)
+	if err := yf.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	ticker, err := yf.Ticker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ticker:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for live ticks")
+		}
+	}
+	yf.Close()
+	rec.Close()
+
+	replay := NewReplay(path, 0, false)
+	replay.Subscribe("EURUSD=X")
+	ticks, err := replay.Ticks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var prices []float32
+	for output := range ticks {
+		prices = append(prices, output.Price)
+	}
+	if len(prices) != 2 || prices[0] != 1.25 || prices[1] != 1.5 {
+		t.Fatalf("unexpected replayed prices: %v", prices)
+	}
+}
+
+func TestReplayLoopAdvancesTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frames.jsonl")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoder := json.NewEncoder(file)
+	for _, ts := range []int64{1000, 1500, 3000} {
+		frame, err := yahootest.Encode(&yatickerpb.Yaticker{Id: "EURUSD=X", Price: 1.25, Time: ts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoder.Encode(frameRecord{At: ts, Frame: string(frame)})
+	}
+	file.Close()
+
+	replay := NewReplay(path, 0, true)
+	replay.Subscribe("EURUSD=X")
+	ticks, err := replay.Ticks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+
+	want := []int64{1000, 1500, 3000, 3000, 3500, 5000}
+	for i, ts := range want {
+		select {
+		case output := <-ticks:
+			if output.Time != ts {
+				t.Fatalf("tick %d has time %d, want %d", i, output.Time, ts)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for replayed ticks")
+		}
+	}
+}
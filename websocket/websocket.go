@@ -1,141 +1,441 @@
 package websocket
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"wsaetherfy/yatickerpb"
-	"net/url"
+	"sync"
 	"time"
+	"wsaetherfy/yatickerpb"
 
 	"github.com/gorilla/websocket"
 	"google.golang.org/protobuf/proto"
 )
 
+// Endereço do streamer do Yahoo usado quando nenhum outro é configurado
+const DefaultURL = "wss://streamer.finance.yahoo.com/"
+
+// Tamanho do buffer do canal de eventos de estado
+const stateBufferSize = 16
+
+// Intervalo padrão dos pings e tempo máximo sem frames antes de reconectar
+const (
+	DefaultPingInterval = 15 * time.Second
+	DefaultReadTimeout  = time.Minute
+)
+
+// ErrClosed é retornado pelas operações feitas depois de Close.
+var ErrClosed = errors.New("yahoo finance: conexão encerrada")
+
 type YahooFinance struct {
+	// mu protege ws, connected e subs, e serializa as escritas na conexão
+	mu        sync.Mutex
 	ws        *websocket.Conn
-	sub       string
+	url       string
+	subs      []string
 	connected bool
-	done      chan bool
+	recorder  *Recorder
+	policy    ReconnectPolicy
+	states    chan StateEvent
+	// Heartbeat: pings periódicos e prazo máximo sem frames
+	pingInterval time.Duration
+	readTimeout  time.Duration
+	// lifetime é cancelado por Close e interrompe leitura, espera e discagem
+	lifetime  context.Context
+	cancel    context.CancelFunc
+	closeOnce sync.Once
 }
 
 func New() *YahooFinance {
-	return &YahooFinance{}
+	lifetime, cancel := context.WithCancel(context.Background())
+	return &YahooFinance{
+		policy:       DefaultReconnectPolicy,
+		states:       make(chan StateEvent, stateBufferSize),
+		pingInterval: DefaultPingInterval,
+		readTimeout:  DefaultReadTimeout,
+		lifetime:     lifetime,
+		cancel:       cancel,
+	}
 }
 
 func NewWithSub(subs string) *YahooFinance {
-	return &YahooFinance{
-		sub: subs,
+	return NewWithSubs(subs)
+}
+
+// NewWithSubs cria uma conexão que assina vários símbolos de uma só vez.
+func NewWithSubs(subs ...string) *YahooFinance {
+	yf := New()
+	yf.addSubs(subs...)
+	return yf
+}
+
+// SetURL troca o endereço do streamer, por exemplo para um servidor de
+// testes. Vale a partir da próxima conexão.
+func (yf *YahooFinance) SetURL(url string) {
+	yf.mu.Lock()
+	defer yf.mu.Unlock()
+	yf.url = url
+}
+
+// SetRecorder grava todos os frames recebidos do streamer no recorder. Deve
+// ser chamada antes de Ticker.
+func (yf *YahooFinance) SetRecorder(rec *Recorder) {
+	yf.recorder = rec
+}
+
+// SetReconnectPolicy define como o Ticker reconecta quando a conexão cai.
+// Deve ser chamada antes de Ticker.
+func (yf *YahooFinance) SetReconnectPolicy(policy ReconnectPolicy) {
+	yf.policy = policy
+}
+
+// SetHeartbeat define o intervalo dos pings e por quanto tempo a conexão pode
+// ficar sem receber frames (ticks, heartbeats ou pongs) antes de ser
+// considerada parada e reconectada. Valores não positivos mantêm o atual,
+// para que uma configuração ausente não desative a detecção de conexões
+// paradas. Deve ser chamada antes de Ticker.
+func (yf *YahooFinance) SetHeartbeat(pingInterval, readTimeout time.Duration) {
+	if pingInterval > 0 {
+		yf.pingInterval = pingInterval
+	} else {
+		fmt.Println("Invalid ping interval, keeping", yf.pingInterval)
+	}
+	if readTimeout > 0 {
+		yf.readTimeout = readTimeout
+	} else {
+		fmt.Println("Invalid read timeout, keeping", yf.readTimeout)
 	}
 }
 
+// States retorna o canal com as mudanças de estado da conexão. Eventos são
+// descartados se ninguém consumir o canal.
+func (yf *YahooFinance) States() <-chan StateEvent {
+	return yf.states
+}
+
+func (yf *YahooFinance) emit(state State, attempt int, err error) {
+	select {
+	case yf.states <- StateEvent{State: state, Attempt: attempt, Err: err, At: time.Now()}:
+	default:
+	}
+}
+
+func (yf *YahooFinance) stopped() bool {
+	return yf.lifetime.Err() != nil
+}
+
+// addSubs registra os símbolos assinados, ignorando vazios e duplicados,
+// para que uma reconexão possa reassinar todos eles. Deve ser chamada com o
+// lock.
+func (yf *YahooFinance) addSubs(subs ...string) {
+	for _, sub := range subs {
+		if sub == "" {
+			continue
+		}
+		exists := false
+		for _, s := range yf.subs {
+			if s == sub {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			yf.subs = append(yf.subs, sub)
+		}
+	}
+}
+
+// Subs retorna os símbolos atualmente assinados.
+func (yf *YahooFinance) Subs() []string {
+	yf.mu.Lock()
+	defer yf.mu.Unlock()
+	return append([]string(nil), yf.subs...)
+}
+
+// Connected informa se há uma conexão aberta com o streamer.
+func (yf *YahooFinance) Connected() bool {
+	yf.mu.Lock()
+	defer yf.mu.Unlock()
+	return yf.connected
+}
+
 func (yf *YahooFinance) Connect() error {
-	u := url.URL{
-		Scheme: "wss",
-		Host:   "streamer.finance.yahoo.com",
-		Path:   "/",
+	return yf.ConnectContext(context.Background())
+}
+
+// ConnectContext conecta ao streamer, desistindo se ctx for cancelado ou se
+// o YahooFinance for fechado durante a discagem.
+func (yf *YahooFinance) ConnectContext(ctx context.Context) error {
+	if yf.stopped() {
+		return ErrClosed
 	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(yf.lifetime, cancel)
+	defer stop()
 
-	var err error
+	yf.mu.Lock()
+	u := yf.url
+	yf.mu.Unlock()
+	if u == "" {
+		u = DefaultURL
+	}
 
-	yf.ws, _, err = websocket.DefaultDialer.Dial(u.String(), nil)
+	ws, _, err := websocket.DefaultDialer.DialContext(ctx, u, nil)
 	if err != nil {
 		fmt.Println("Fail to Dial: ", err)
 		return err
 	}
+
+	yf.mu.Lock()
+	defer yf.mu.Unlock()
+	if yf.stopped() {
+		ws.Close()
+		return ErrClosed
+	}
+	if yf.connected {
+		yf.ws.Close()
+	}
+	yf.ws = ws
 	yf.connected = true
+	yf.emit(StateConnected, 0, nil)
 	return nil
 }
 
+// disconnect fecha a conexão informada se ela ainda for a atual.
+func (yf *YahooFinance) disconnect(ws *websocket.Conn) {
+	yf.mu.Lock()
+	defer yf.mu.Unlock()
+	ws.Close()
+	if yf.ws == ws {
+		yf.connected = false
+	}
+}
+
+// Close encerra a conexão e o leitor iniciado por Ticker, que fecha o canal
+// de ticks em vez de reconectar. Depois de Close o YahooFinance não pode ser
+// reutilizado.
 func (yf *YahooFinance) Close() error {
+	yf.closeOnce.Do(func() {
+		yf.cancel()
+		yf.emit(StateClosed, 0, nil)
+	})
+	yf.mu.Lock()
+	defer yf.mu.Unlock()
 	if !yf.connected {
 		return nil
 	}
-	yf.done = make(chan bool, 2)
-	yf.done <- true
-	yf.ws.Close()
 	yf.connected = false
-	return nil
+	return yf.ws.Close()
 }
 
-func (yf *YahooFinance) Subscribe(subs ...string) error {
-	if !yf.connected {
-		if err := yf.Connect(); err != nil {
-			fmt.Println("Fail to connect to WS: ", err)
-			return err
-		}
-	}
-	subs = append(subs, yf.sub)
-	body := map[string][]string{
-		"subscribe": subs,
-	}
-	message, err := json.Marshal(body)
+// send escreve uma mensagem de controle ({"subscribe":[...]} ou
+// {"unsubscribe":[...]}) na conexão atual.
+func (yf *YahooFinance) send(action string, subs []string) error {
+	message, err := json.Marshal(map[string][]string{action: subs})
 	if err != nil {
 		fmt.Println("Fail to Unmarshal Body: ", err)
 		return err
 	}
 
-	err = yf.ws.WriteMessage(websocket.TextMessage, message)
-	if err != nil {
+	yf.mu.Lock()
+	defer yf.mu.Unlock()
+	if !yf.connected {
+		return fmt.Errorf("yahoo finance: não conectado")
+	}
+	if err := yf.ws.WriteMessage(websocket.TextMessage, message); err != nil {
 		fmt.Println("Fail to Write Message: ", err)
 		return err
 	}
 	return nil
 }
 
-func (yf *YahooFinance) Ticker() (chan *yatickerpb.Yaticker, error) {
-	if !yf.connected {
+func (yf *YahooFinance) Subscribe(subs ...string) error {
+	if !yf.Connected() {
 		if err := yf.Connect(); err != nil {
+			fmt.Println("Fail to connect to WS: ", err)
+			return err
+		}
+	}
+	yf.mu.Lock()
+	yf.addSubs(subs...)
+	yf.mu.Unlock()
+	return yf.send("subscribe", yf.Subs())
+}
+
+// Unsubscribe cancela a assinatura dos símbolos informados.
+func (yf *YahooFinance) Unsubscribe(subs ...string) error {
+	yf.mu.Lock()
+	yf.subs = removeSubs(yf.subs, subs...)
+	connected := yf.connected
+	yf.mu.Unlock()
+	if !connected {
+		return nil
+	}
+	return yf.send("unsubscribe", subs)
+}
+
+// Ticks implementa Provider sobre Ticker.
+func (yf *YahooFinance) Ticks() (<-chan *yatickerpb.Yaticker, error) {
+	return yf.Ticker()
+}
+
+func (yf *YahooFinance) Ticker() (chan *yatickerpb.Yaticker, error) {
+	return yf.TickerContext(context.Background())
+}
+
+// TickerContext inicia a leitura dos ticks. Quando ctx é cancelado ou Close é
+// chamado, a conexão é fechada, a goroutine de leitura termina e o canal é
+// fechado.
+func (yf *YahooFinance) TickerContext(ctx context.Context) (chan *yatickerpb.Yaticker, error) {
+	if yf.stopped() {
+		return nil, ErrClosed
+	}
+	if !yf.Connected() {
+		if err := yf.ConnectContext(ctx); err != nil {
 			fmt.Println("Fail to connect to WS: ", err)
 			return nil, err
 		}
 	}
+
+	stop := context.AfterFunc(ctx, func() { yf.Close() })
 	output := make(chan *yatickerpb.Yaticker, 10)
 	go func() {
 		defer close(output)
+		defer stop()
 		for {
-			if err := yf.readMessages(output); err != nil {
-				fmt.Println("Fail to Read Message: ", err)
-				// Tenta reconectar
-				yf.Close()
-				time.Sleep(1 * time.Second)
-				if err := yf.Connect(); err != nil {
-					fmt.Println("Fail to reconnect to WS: ", err)
-					break
-				}
-				yf.Subscribe()
-				fmt.Println("WS Conection Restored")
+			yf.mu.Lock()
+			ws := yf.ws
+			yf.mu.Unlock()
+
+			pinging := make(chan struct{})
+			go yf.ping(ws, pinging)
+			err := yf.readMessages(ws, output)
+			close(pinging)
+			if yf.stopped() {
+				return
 			}
+			fmt.Println("Fail to Read Message: ", err)
+			yf.emit(StateDisconnected, 0, err)
+			yf.disconnect(ws)
+			if !yf.reconnect() {
+				return
+			}
+			fmt.Println("WS Conection Restored")
 		}
 	}()
 	return output, nil
 }
 
-func (yf *YahooFinance) readMessages(output chan *yatickerpb.Yaticker) error {
+// reconnect tenta restabelecer a conexão e reassinar todos os símbolos
+// seguindo a política de reconexão. Retorna false quando as tentativas se
+// esgotam ou o YahooFinance é fechado.
+func (yf *YahooFinance) reconnect() bool {
+	for attempt := 1; yf.policy.MaxAttempts == 0 || attempt <= yf.policy.MaxAttempts; attempt++ {
+		delay := yf.policy.Delay(attempt)
+		yf.emit(StateReconnecting, attempt, nil)
+		select {
+		case <-time.After(delay):
+		case <-yf.lifetime.Done():
+			return false
+		}
+
+		if err := yf.ConnectContext(yf.lifetime); err != nil {
+			if yf.stopped() {
+				return false
+			}
+			fmt.Printf("Fail to reconnect to WS (attempt %d): %v\n", attempt, err)
+			continue
+		}
+		if err := yf.send("subscribe", yf.Subs()); err != nil {
+			yf.mu.Lock()
+			ws := yf.ws
+			yf.mu.Unlock()
+			yf.disconnect(ws)
+			continue
+		}
+		return true
+	}
+	fmt.Println("Giving up reconnecting to WS")
+	yf.emit(StateGaveUp, yf.policy.MaxAttempts, nil)
+	return false
+}
+
+// ping envia pings periódicos na conexão até done ser fechado.
+func (yf *YahooFinance) ping(ws *websocket.Conn, done chan struct{}) {
+	if yf.pingInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(yf.pingInterval)
+	defer ticker.Stop()
 	for {
 		select {
-		case <-yf.done:
-			return nil
-		default:
-			_, message, err := yf.ws.ReadMessage()
+		case <-ticker.C:
+			// WriteControl pode ser chamado junto com as demais escritas
+			err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(yf.pingInterval))
 			if err != nil {
-				return err
-			} else {
-				decodedMessage := make([]byte, base64.StdEncoding.DecodedLen(len(message)))
-				l, err := base64.StdEncoding.Decode(decodedMessage, message)
-				if err != nil {
-					fmt.Println("Fail to Decode Message: ", err)
-					continue
-				}
-
-				yaticker := &yatickerpb.Yaticker{}
-				if err := proto.Unmarshal(decodedMessage[:l], yaticker); err != nil {
-					fmt.Println("Fail to Unmarshal Message: ", err)
-					continue
-				}
-
-				output <- yaticker
+				return
 			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// extendDeadline renova o prazo de leitura a cada sinal de vida da conexão.
+func (yf *YahooFinance) extendDeadline(ws *websocket.Conn) error {
+	if yf.readTimeout <= 0 {
+		return nil
+	}
+	return ws.SetReadDeadline(time.Now().Add(yf.readTimeout))
+}
+
+func (yf *YahooFinance) readMessages(ws *websocket.Conn, output chan *yatickerpb.Yaticker) error {
+	yf.extendDeadline(ws)
+	ws.SetPongHandler(func(string) error {
+		return yf.extendDeadline(ws)
+	})
+	for {
+		_, message, err := ws.ReadMessage()
+		if err != nil {
+			return err
 		}
+		yf.extendDeadline(ws)
+		if yf.recorder != nil {
+			yf.recorder.Record(message)
+		}
+
+		yaticker, err := decodeFrame(message)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+
+		// Heartbeats só indicam que a conexão está viva
+		if yaticker.QuoteType == yatickerpb.Yaticker_HEARTBEAT {
+			continue
+		}
+		select {
+		case output <- yaticker:
+		case <-yf.lifetime.Done():
+			return nil
+		}
+	}
+}
+
+// decodeFrame converte um frame do streamer (protobuf em base64) em ticker.
+func decodeFrame(message []byte) (*yatickerpb.Yaticker, error) {
+	decodedMessage := make([]byte, base64.StdEncoding.DecodedLen(len(message)))
+	l, err := base64.StdEncoding.Decode(decodedMessage, message)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to Decode Message: %v", err)
+	}
+
+	yaticker := &yatickerpb.Yaticker{}
+	if err := proto.Unmarshal(decodedMessage[:l], yaticker); err != nil {
+		return nil, fmt.Errorf("Fail to Unmarshal Message: %v", err)
 	}
+	return yaticker, nil
 }
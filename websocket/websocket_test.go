@@ -1,10 +1,23 @@
 package websocket
 
-import "testing"
+import (
+	"context"
+	"testing"
+	"time"
+	"wsaetherfy/websocket/yahootest"
+	"wsaetherfy/yatickerpb"
+)
 
 func TestYFTicker(t *testing.T) {
+	server := yahootest.NewServer(
+		yahootest.Tick("BTC-USD", 60000, 1),
+		yahootest.Tick("EURUSD=X", 1.25, 2),
+	)
+	defer server.Close()
+
 	subs := "EURUSD=X"
 	yf := NewWithSub(subs)
+	yf.SetURL(server.URL())
 	if err := yf.Connect(); err != nil {
 		t.Fail()
 		return
@@ -20,8 +33,153 @@ func TestYFTicker(t *testing.T) {
 	}
 	output := <-ticker
 	yf.Close()
-	if output.Id == "" {
-		t.Fail()
-		return
+	if output.Id != subs || output.Price != 1.25 {
+		t.Fatalf("unexpected ticker: %+v", output)
+	}
+}
+
+func TestYFReconnectResubscribesAll(t *testing.T) {
+	server := yahootest.NewServer(
+		yahootest.Tick("EURUSD=X", 1.25, 1),
+		yahootest.Step{Disconnect: true},
+		yahootest.Tick("BTC-USD", 60000, 2),
+	)
+	defer server.Close()
+
+	yf := NewWithSub("EURUSD=X")
+	yf.SetURL(server.URL())
+	yf.SetReconnectPolicy(ReconnectPolicy{InitialDelay: time.Millisecond, Multiplier: 2, MaxAttempts: 5})
+	if err := yf.Subscribe("BTC-USD"); err != nil {
+		t.Fatal(err)
+	}
+	ticker, err := yf.Ticker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer yf.Close()
+
+	for _, want := range []string{"EURUSD=X", "BTC-USD"} {
+		select {
+		case output := <-ticker:
+			if output.Id != want {
+				t.Fatalf("got %s, want %s", output.Id, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+	}
+
+	subs := server.Subscriptions()
+	if server.Connections() != 2 || len(subs) != 2 || len(subs[1]) != 2 {
+		t.Fatalf("expected both symbols resubscribed on a second connection, got %v", subs)
+	}
+
+	var states []State
+	for len(yf.States()) > 0 {
+		states = append(states, (<-yf.States()).State)
+	}
+	want := []State{StateConnected, StateDisconnected, StateReconnecting, StateConnected}
+	if len(states) != len(want) {
+		t.Fatalf("states = %v, want %v", states, want)
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Fatalf("states = %v, want %v", states, want)
+		}
+	}
+}
+
+func TestReconnectPolicyDelay(t *testing.T) {
+	p := ReconnectPolicy{InitialDelay: time.Second, MaxDelay: 5 * time.Second, Multiplier: 2}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second} {
+		if got := p.Delay(attempt); got != want {
+			t.Errorf("Delay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
+
+func TestYFTickerContextCancel(t *testing.T) {
+	server := yahootest.NewServer(yahootest.Tick("EURUSD=X", 1.25, 1))
+	defer server.Close()
+
+	yf := NewWithSub("EURUSD=X")
+	yf.SetURL(server.URL())
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := yf.ConnectContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := yf.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	ticker, err := yf.TickerContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-ticker
+
+	// O leitor está bloqueado em ReadMessage; o cancelamento deve encerrá-lo
+	cancel()
+	select {
+	case _, ok := <-ticker:
+		if ok {
+			t.Fatal("unexpected tick after cancel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ticker channel was not closed after cancel")
+	}
+	if yf.Connected() {
+		t.Fatal("still connected after cancel")
+	}
+	if _, err := yf.Ticker(); err != ErrClosed {
+		t.Fatalf("Ticker after cancel returned %v, want ErrClosed", err)
+	}
+}
+
+func TestYFReconnectsStaleFeed(t *testing.T) {
+	server := yahootest.NewServer(
+		yahootest.Heartbeat(),
+		yahootest.Tick("EURUSD=X", 1.25, 1),
+		yahootest.Step{Delay: time.Second, Ticker: &yatickerpb.Yaticker{Id: "EURUSD=X", Price: 1.5, Time: 2}},
+		yahootest.Tick("EURUSD=X", 1.75, 3),
+	)
+	defer server.Close()
+	server.SetIgnorePings(true)
+
+	yf := NewWithSub("EURUSD=X")
+	yf.SetURL(server.URL())
+	yf.SetHeartbeat(time.Hour, 200*time.Millisecond)
+	yf.SetReconnectPolicy(ReconnectPolicy{InitialDelay: time.Millisecond, Multiplier: 1})
+	if err := yf.Subscribe(); err != nil {
+		t.Fatal(err)
+	}
+	ticker, err := yf.Ticker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer yf.Close()
+
+	// O heartbeat não é repassado como tick
+	output := <-ticker
+	if output.Price != 1.25 {
+		t.Fatalf("unexpected first tick: %+v", output)
+	}
+
+	// O servidor fica mudo por mais que o prazo de leitura, então o cliente
+	// reconecta e recebe o restante do roteiro pela nova conexão
+	select {
+	case output = <-ticker:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for tick after reconnect")
+	}
+	if server.Connections() < 2 {
+		t.Fatalf("expected a reconnection, got %d connections", server.Connections())
+	}
+}
+
+func TestSetHeartbeatKeepsDefaults(t *testing.T) {
+	yf := New()
+	yf.SetHeartbeat(0, -time.Second)
+	if yf.pingInterval != DefaultPingInterval || yf.readTimeout != DefaultReadTimeout {
+		t.Fatalf("heartbeat = %s/%s, want the defaults", yf.pingInterval, yf.readTimeout)
 	}
 }
@@ -0,0 +1,214 @@
+// Package yahootest fornece um streamer falso do Yahoo Finance para testes
+// sem acesso à rede.
+package yahootest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+	"wsaetherfy/yatickerpb"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/proto"
+)
+
+// Step é um passo do roteiro do servidor: espera Delay e então envia Ticker
+// (se o símbolo estiver assinado) ou, com Disconnect, derruba a conexão.
+type Step struct {
+	Delay      time.Duration
+	Ticker     *yatickerpb.Yaticker
+	Disconnect bool
+}
+
+// Tick cria um passo que envia um tick com o preço e o horário informados.
+func Tick(id string, price float32, time int64) Step {
+	return Step{Ticker: &yatickerpb.Yaticker{Id: id, Price: price, Time: time}}
+}
+
+// Heartbeat cria um passo que envia um heartbeat, entregue mesmo sem assinatura.
+func Heartbeat() Step {
+	return Step{Ticker: &yatickerpb.Yaticker{QuoteType: yatickerpb.Yaticker_HEARTBEAT}}
+}
+
+// Encode converte o tick no frame enviado pelo streamer real: o protobuf
+// codificado em base64.
+func Encode(ticker *yatickerpb.Yaticker) ([]byte, error) {
+	data, err := proto.Marshal(ticker)
+	if err != nil {
+		return nil, err
+	}
+	frame := make([]byte, base64.StdEncoding.EncodedLen(len(data)))
+	base64.StdEncoding.Encode(frame, data)
+	return frame, nil
+}
+
+// Server é um streamer falso baseado em httptest. Ele aceita os frames
+// {"subscribe":[...]} e {"unsubscribe":[...]} e reproduz o roteiro uma única
+// vez: depois de um Disconnect, a próxima conexão continua do passo seguinte.
+type Server struct {
+	*httptest.Server
+	sync.Mutex
+	steps       []Step
+	next        int
+	connections int
+	subscribed  [][]string
+	conns       map[*websocket.Conn]struct{}
+	ignorePings bool
+	upgrader    websocket.Upgrader
+}
+
+// NewServer inicia o servidor com o roteiro informado.
+func NewServer(steps ...Step) *Server {
+	s := &Server{steps: steps, conns: make(map[*websocket.Conn]struct{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// URL retorna o endereço ws:// do servidor.
+func (s *Server) URL() string {
+	return "ws" + strings.TrimPrefix(s.Server.URL, "http")
+}
+
+// Close derruba as conexões abertas e encerra o servidor.
+func (s *Server) Close() {
+	s.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.Unlock()
+	s.Server.Close()
+}
+
+// SetIgnorePings faz o servidor não responder aos pings dos clientes, como um
+// upstream travado.
+func (s *Server) SetIgnorePings(ignore bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.ignorePings = ignore
+}
+
+// Connections retorna quantas conexões o servidor recebeu.
+func (s *Server) Connections() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.connections
+}
+
+// Subscriptions retorna as listas de símbolos recebidas em cada subscribe.
+func (s *Server) Subscriptions() [][]string {
+	s.Lock()
+	defer s.Unlock()
+	return append([][]string(nil), s.subscribed...)
+}
+
+// nextStep avança o roteiro compartilhado entre as conexões.
+func (s *Server) nextStep() (Step, bool) {
+	s.Lock()
+	defer s.Unlock()
+	if s.next >= len(s.steps) {
+		return Step{}, false
+	}
+	step := s.steps[s.next]
+	s.next++
+	return step, true
+}
+
+type control struct {
+	Subscribe   []string `json:"subscribe"`
+	Unsubscribe []string `json:"unsubscribe"`
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	s.Lock()
+	s.connections++
+	s.conns[conn] = struct{}{}
+	if s.ignorePings {
+		conn.SetPingHandler(func(string) error { return nil })
+	}
+	s.Unlock()
+	defer func() {
+		s.Lock()
+		delete(s.conns, conn)
+		s.Unlock()
+	}()
+
+	var mu sync.Mutex
+	subs := make(map[string]bool)
+	subscribed := make(chan struct{})
+	closed := make(chan struct{})
+	var once sync.Once
+	go func() {
+		defer close(closed)
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var c control
+			if err := json.Unmarshal(message, &c); err != nil {
+				continue
+			}
+			mu.Lock()
+			for _, sub := range c.Subscribe {
+				subs[sub] = true
+			}
+			for _, sub := range c.Unsubscribe {
+				delete(subs, sub)
+			}
+			mu.Unlock()
+			if c.Subscribe != nil {
+				s.Lock()
+				s.subscribed = append(s.subscribed, c.Subscribe)
+				s.Unlock()
+				once.Do(func() { close(subscribed) })
+			}
+		}
+	}()
+
+	// O streamer só começa a enviar ticks depois da primeira assinatura
+	select {
+	case <-subscribed:
+	case <-closed:
+		return
+	}
+
+	for {
+		step, ok := s.nextStep()
+		if !ok {
+			break
+		}
+		time.Sleep(step.Delay)
+		if step.Disconnect {
+			return
+		}
+		if step.Ticker == nil {
+			continue
+		}
+		mu.Lock()
+		active := subs[step.Ticker.Id] || step.Ticker.QuoteType == yatickerpb.Yaticker_HEARTBEAT
+		mu.Unlock()
+		if !active {
+			continue
+		}
+		frame, err := Encode(step.Ticker)
+		if err != nil {
+			return
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+			return
+		}
+	}
+
+	// Roteiro encerrado: mantém a conexão aberta até o cliente sair
+	<-closed
+}
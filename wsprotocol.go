@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"wsaetherfy/currency"
+)
+
+// Tamanho máximo de uma mensagem de controle enviada pelo cliente em /ws
+const wsMaxMessageSize = 4096
+
+// Canais disponíveis em /ws
+const (
+	wsChannelPrices  = "prices"
+	wsChannelCandles = "candles"
+)
+
+// Mensagem de controle enviada pelo cliente em /ws, por exemplo
+// {"action":"subscribe","pairs":["EUR/USD","BTC/USD"]}. Para receber candles,
+// informe {"channel":"candles","interval":"1m"}. Ações, índices e outros
+// instrumentos são assinados pelo ticker, em pairs ou em symbols.
+type wsCommand struct {
+	Action   string   `json:"action"`
+	Pairs    []string `json:"pairs"`
+	Symbols  []string `json:"symbols,omitempty"`
+	Channel  string   `json:"channel,omitempty"`
+	Interval string   `json:"interval,omitempty"`
+}
+
+type wsPairError struct {
+	Pair  string `json:"pair"`
+	Error string `json:"error"`
+}
+
+// Resposta às mensagens de controle
+type wsAck struct {
+	Type     string              `json:"type"`
+	Action   string              `json:"action"`
+	Channel  string              `json:"channel,omitempty"`
+	Interval string              `json:"interval,omitempty"`
+	Pairs    []string            `json:"pairs"`
+	Candles  map[string][]string `json:"candles,omitempty"`
+	Errors   []wsPairError       `json:"errors,omitempty"`
+}
+
+type wsError struct {
+	Type  string `json:"type"`
+	Error string `json:"error"`
+}
+
+// parseWsCommand interpreta uma mensagem do cliente. Mensagens que não são
+// JSON são tratadas como o protocolo antigo: um único par a ser assinado.
+func parseWsCommand(message []byte) (wsCommand, error) {
+	text := strings.TrimSpace(string(message))
+	if !strings.HasPrefix(text, "{") {
+		return wsCommand{Action: "subscribe", Pairs: []string{text}}, nil
+	}
+	var cmd wsCommand
+	if err := json.Unmarshal([]byte(text), &cmd); err != nil {
+		return wsCommand{}, fmt.Errorf("mensagem de controle inválida: %v", err)
+	}
+	cmd.Pairs = append(cmd.Pairs, cmd.Symbols...)
+	cmd.Symbols = nil
+	return cmd, nil
+}
+
+// wsTopic converte o par no tópico do hub correspondente ao canal do comando.
+func wsTopic(cmd wsCommand, pair string) string {
+	if cmd.Channel == wsChannelCandles {
+		return currency.CandleTopic(cmd.Interval, pair)
+	}
+	return pair
+}
+
+// handleWsCommand aplica a mensagem de controle ao assinante e monta a
+// resposta a ser enviada ao cliente.
+func handleWsCommand(sub *currency.Subscriber, cmd wsCommand) interface{} {
+	switch cmd.Channel {
+	case "", wsChannelPrices:
+		cmd.Channel = wsChannelPrices
+		cmd.Interval = ""
+	case wsChannelCandles:
+		if cmd.Action != "list" && !currency.IsCandleInterval(cmd.Interval) {
+			return wsError{Type: "error", Error: fmt.Sprintf("Intervalo inválido: %q (use %s)", cmd.Interval, strings.Join(currency.CandleIntervals(), ", "))}
+		}
+	default:
+		return wsError{Type: "error", Error: fmt.Sprintf("Canal desconhecido: %q", cmd.Channel)}
+	}
+
+	switch cmd.Action {
+	case "subscribe":
+		ack := wsAck{Type: "ack", Action: cmd.Action, Channel: cmd.Channel, Interval: cmd.Interval, Pairs: []string{}}
+		for _, pair := range cmd.Pairs {
+			_, exists := currency.GetCurrencyCode(pair)
+			// Taxas sintéticas só estão disponíveis no canal de preços
+			cross := !exists && cmd.Channel == wsChannelPrices && currency.IsCrossPair(pair)
+			if !exists && !cross {
+				ack.Errors = append(ack.Errors, wsPairError{Pair: pair, Error: "Par de moedas inválido"})
+				continue
+			}
+			sub.Subscribe(wsTopic(cmd, pair))
+			if cross {
+				currency.WatchCrossPair(pair)
+			}
+			ack.Pairs = append(ack.Pairs, pair)
+		}
+		return ack
+	case "unsubscribe":
+		ack := wsAck{Type: "ack", Action: cmd.Action, Channel: cmd.Channel, Interval: cmd.Interval, Pairs: []string{}}
+		subscribed := make(map[string]bool)
+		for _, topic := range sub.Topics() {
+			subscribed[topic] = true
+		}
+		for _, pair := range cmd.Pairs {
+			topic := wsTopic(cmd, pair)
+			if !subscribed[topic] {
+				ack.Errors = append(ack.Errors, wsPairError{Pair: pair, Error: "Par de moedas não assinado"})
+				continue
+			}
+			sub.Unsubscribe(topic)
+			ack.Pairs = append(ack.Pairs, pair)
+		}
+		return ack
+	case "list":
+		ack := wsAck{Type: "list", Action: cmd.Action, Pairs: []string{}}
+		topics := sub.Topics()
+		sort.Strings(topics)
+		for _, topic := range topics {
+			if interval, pair, ok := currency.ParseCandleTopic(topic); ok {
+				if ack.Candles == nil {
+					ack.Candles = make(map[string][]string)
+				}
+				ack.Candles[interval] = append(ack.Candles[interval], pair)
+				continue
+			}
+			ack.Pairs = append(ack.Pairs, topic)
+		}
+		return ack
+	default:
+		return wsError{Type: "error", Error: fmt.Sprintf("Ação desconhecida: %q", cmd.Action)}
+	}
+}